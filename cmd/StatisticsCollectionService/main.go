@@ -1,15 +1,19 @@
 package main
 
 import (
-	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/app"
 	"StatisticsCollectionService/internal/db"
-	"StatisticsCollectionService/internal/repository"
-	"StatisticsCollectionService/internal/services"
+	"StatisticsCollectionService/internal/logging"
+	"StatisticsCollectionService/internal/tracing"
+	"context"
 	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "StatisticsCollectionService/docs"
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // @title API Сервиса Сбора Статистики
@@ -17,22 +21,91 @@ import (
 // @description Это микросервис на golang для сбора статистики
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 
 // Основная функция для запуска сервера
 func main() {
-	db.InitDB()
-	defer db.DB.Close()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	logger, err := logging.New(cfg.Logging, os.Stderr)
+	if err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	// Стандартный log, которым пользуются подкоманды, тоже пишет через slog
+	slog.SetDefault(logger)
 
-	repo := repository.NewPostgresRepository(db.DB)
-	service := services.NewService(repo)
+	if len(args) > 0 && args[0] == "migrate" {
+		conn, dialect := openMigrationDB(cfg)
+		defer conn.Close()
+		runMigrate(conn, dialect, args[1:])
+		return
+	}
 
-	http.HandleFunc("/orderbook/get", api.GetOrderBookHandler(service))
-	http.HandleFunc("/orderbook/save", api.SaveOrderBookHandler(service))
-	http.HandleFunc("/orderhistory/get", api.GetOrderHistoryHandler(service))
-	http.HandleFunc("/order/save", api.SaveOrderHandler(service))
+	if len(args) > 0 && args[0] == "partitions" {
+		if cfg.Storage.Driver != config.StoragePostgres {
+			log.Fatalf("Partitions are not supported by the %q storage driver", cfg.Storage.Driver)
+		}
+		conn, err := db.Open(cfg.Database)
+		if err != nil {
+			log.Fatalf("Error opening database: %v", err)
+		}
+		defer conn.Close()
+		runPartitions(app.NewPartitionManager(conn, cfg.Partitions), args[1:])
+		return
+	}
 
-	// Swagger endpoint
-	http.Handle("/swagger/", httpSwagger.WrapHandler)
-	log.Println("Server started at :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	if len(args) > 0 && args[0] == "retention" {
+		if cfg.Storage.Driver == config.StorageMemory {
+			log.Fatalf("Retention is not supported by the %q storage driver", cfg.Storage.Driver)
+		}
+		storage, err := app.OpenStorage(cfg)
+		if err != nil {
+			log.Fatalf("Error opening storage: %v", err)
+		}
+		defer storage.Close()
+		runRetention(storage.DB, storage.Repository, cfg.Retention, args[1:])
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Error configuring tracing: %v", err)
+	}
+
+	application, err := app.New(cfg)
+	if err != nil {
+		log.Fatalf("Error starting service: %v", err)
+	}
+	if err := application.Start(); err != nil {
+		log.Fatalf("Error starting service: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		// Повторный сигнал завершает процесс без ожидания
+		stop()
+		slog.Info("Received shutdown signal")
+	case serveErr = <-application.Errors():
+		slog.Error("Server error", "error", serveErr)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Error shutting down: %v", err)
+	}
+	// Отправка spans, накопленных за время остановки
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"StatisticsCollectionService/internal/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// Функция для выполнения команды migrate up/down/status
+func runMigrate(db *sql.DB, dialect migrations.Dialect, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: migrate up|down [steps]|status")
+	}
+
+	migrator, err := migrations.NewMigrator(db, dialect)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatalf("Invalid number of steps: %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error reverting migrations: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"StatisticsCollectionService/internal/partitions"
+	"context"
+	"fmt"
+	"log"
+)
+
+// Функция для выполнения команды partitions status/ensure/expire/maintain
+func runPartitions(manager *partitions.Manager, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: partitions status|ensure|expire|maintain")
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		list, err := manager.Status(ctx)
+		if err != nil {
+			log.Fatalf("Error reading partitions: %v", err)
+		}
+		for _, p := range list {
+			if p.Default {
+				fmt.Printf("%s\tDEFAULT\t~%d rows\n", p.Name, p.Rows)
+				continue
+			}
+			fmt.Printf("%s\t%s .. %s\t~%d rows\n", p.Name, p.From.Format("2006-01-02"), p.To.Format("2006-01-02"), p.Rows)
+		}
+	case "ensure":
+		created, err := manager.Ensure(ctx)
+		logPartitions("Created", created)
+		if err != nil {
+			log.Fatalf("Error creating partitions: %v", err)
+		}
+	case "expire":
+		expired, err := manager.Expire(ctx)
+		logPartitions("Expired", expired)
+		if err != nil {
+			log.Fatalf("Error expiring partitions: %v", err)
+		}
+	case "maintain":
+		report, err := manager.Maintain(ctx)
+		logPartitions("Created", report.Created)
+		logPartitions("Expired", report.Expired)
+		if err != nil {
+			log.Fatalf("Error maintaining partitions: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown partitions command %q", args[0])
+	}
+}
+
+func logPartitions(action string, list []partitions.Partition) {
+	for _, p := range list {
+		log.Printf("%s partition %s", action, p.Name)
+	}
+	if len(list) == 0 {
+		log.Printf("%s no partitions", action)
+	}
+}
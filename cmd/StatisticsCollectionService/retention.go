@@ -0,0 +1,79 @@
+package main
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/app"
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/retention"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Функция для выполнения команды retention policy/run/import
+func runRetention(db *sql.DB, repo repository.Repository, cfg config.RetentionConfig, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: retention policy|run|import FILE...")
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "policy":
+		fmt.Printf("order_books\t%s\n", retentionPeriod(cfg.OrderBooks))
+		fmt.Printf("order_history\t%s\n", retentionPeriod(cfg.OrderHistory))
+		clients, err := retention.ParseClientOverrides(cfg.Clients)
+		if err != nil {
+			log.Fatalf("Error loading retention policy: %v", err)
+		}
+		names := make([]string, 0, len(clients))
+		for name := range clients {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("order_history[%s]\t%s\n", name, retentionPeriod(clients[name]))
+		}
+	case "run":
+		archiver, err := app.NewArchiver(db, cfg)
+		if err != nil {
+			log.Fatalf("Error creating archiver: %v", err)
+		}
+		result, err := archiver.Archive(ctx)
+		for _, path := range result.Files {
+			log.Printf("Written archive %s", path)
+		}
+		log.Printf("Archived %d order books and %d orders", result.OrderBooks, result.OrderHistory)
+		if err != nil {
+			log.Fatalf("Error archiving expired data: %v", err)
+		}
+	case "import":
+		if len(args) < 2 {
+			log.Fatal("Usage: retention import FILE...")
+		}
+		for _, path := range args[1:] {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Fatalf("Error opening archive: %v", err)
+			}
+			n, err := retention.Import(ctx, f, repo)
+			f.Close()
+			if err != nil {
+				log.Fatalf("Error importing %s: %v", path, err)
+			}
+			log.Printf("Imported %d records from %s", n, path)
+		}
+	default:
+		log.Fatalf("Unknown retention command %q", args[0])
+	}
+}
+
+func retentionPeriod(d time.Duration) string {
+	if d.Hours() == 0 {
+		return "forever"
+	}
+	return fmt.Sprintf("%.0f days", d.Hours()/24)
+}
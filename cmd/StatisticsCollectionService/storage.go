@@ -0,0 +1,30 @@
+package main
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/migrations"
+	"database/sql"
+	"log"
+)
+
+// Функция открытия базы данных для выполнения миграций
+func openMigrationDB(cfg *config.Config) (*sql.DB, migrations.Dialect) {
+	switch cfg.Storage.Driver {
+	case config.StorageSQLite:
+		sqliteDB, err := db.OpenSQLite(cfg.Storage)
+		if err != nil {
+			log.Fatalf("Error opening database: %v", err)
+		}
+		return sqliteDB, migrations.SQLite
+	case config.StoragePostgres:
+		postgresDB, err := db.Open(cfg.Database)
+		if err != nil {
+			log.Fatalf("Error opening database: %v", err)
+		}
+		return postgresDB, migrations.Postgres
+	default:
+		log.Fatalf("Migrations are not supported by the %q storage driver", cfg.Storage.Driver)
+		return nil, migrations.Dialect{}
+	}
+}
@@ -1 +1,811 @@
 package config
+
+import (
+	"StatisticsCollectionService/internal/partitions"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Префикс переменных окружения с настройками сервиса
+const EnvPrefix = "STATS_"
+
+// Конфигурация сервиса
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	GRPC        GRPCConfig        `yaml:"grpc" toml:"grpc"`
+	Storage     StorageConfig     `yaml:"storage" toml:"storage"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Timeouts    TimeoutsConfig    `yaml:"timeouts" toml:"timeouts"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	WriteBuffer WriteBufferConfig `yaml:"write_buffer" toml:"write_buffer"`
+	Partitions  PartitionsConfig  `yaml:"partitions" toml:"partitions"`
+	Retention   RetentionConfig   `yaml:"retention" toml:"retention"`
+	Health      HealthConfig      `yaml:"health" toml:"health"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Logging     LoggingConfig     `yaml:"logging" toml:"logging"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Metrics     MetricsConfig     `yaml:"metrics" toml:"metrics"`
+	Features    FeaturesConfig    `yaml:"features" toml:"features"`
+}
+
+// Настройки HTTP-сервера
+type ServerConfig struct {
+	Addr            string        `yaml:"addr" toml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// Время между переводом /readyz в состояние остановки и закрытием порта,
+	// за которое балансировщик успевает исключить сервис
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// Настройки gRPC-сервера. Reflection включает сервис рефлексии, через который
+// grpcurl и подобные инструменты получают описание API.
+type GRPCConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	Addr       string `yaml:"addr" toml:"addr"`
+	Reflection bool   `yaml:"reflection" toml:"reflection"`
+}
+
+// Драйверы хранилища данных
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+	StorageSQLite   = "sqlite"
+)
+
+// Настройки хранилища данных
+type StorageConfig struct {
+	Driver            string        `yaml:"driver" toml:"driver"`
+	SQLitePath        string        `yaml:"sqlite_path" toml:"sqlite_path"`
+	SQLiteBusyTimeout time.Duration `yaml:"sqlite_busy_timeout" toml:"sqlite_busy_timeout"`
+}
+
+// Настройки подключения к базе данных
+type DatabaseConfig struct {
+	DSN             string        `yaml:"dsn" toml:"dsn"`
+	Host            string        `yaml:"host" toml:"host"`
+	Port            int           `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	PasswordFile    string        `yaml:"password_file" toml:"password_file"`
+	Name            string        `yaml:"name" toml:"name"`
+	SSLMode         string        `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	// Строки подключения к репликам, с которых выполняется чтение. Из файла
+	// ReplicasFile они читаются по одной на строку и заменяют Replicas.
+	Replicas             []string      `yaml:"replicas" toml:"replicas"`
+	ReplicasFile         string        `yaml:"replicas_file" toml:"replicas_file"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" toml:"replica_check_interval"`
+}
+
+// Ограничения времени обработки запросов по эндпоинтам.
+// Нулевое значение означает использование таймаута по умолчанию.
+type TimeoutsConfig struct {
+	Default         time.Duration `yaml:"default" toml:"default"`
+	GetOrderBook    time.Duration `yaml:"get_order_book" toml:"get_order_book"`
+	SaveOrderBook   time.Duration `yaml:"save_order_book" toml:"save_order_book"`
+	GetOrderHistory time.Duration `yaml:"get_order_history" toml:"get_order_history"`
+	SaveOrder       time.Duration `yaml:"save_order" toml:"save_order"`
+}
+
+// Метод для получения таймаута эндпоинта с учетом значения по умолчанию
+func (c TimeoutsConfig) For(endpoint time.Duration) time.Duration {
+	if endpoint > 0 {
+		return endpoint
+	}
+	return c.Default
+}
+
+// Настройки кэша книг ордеров
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled"`
+	Size    int           `yaml:"size" toml:"size"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl"`
+}
+
+// Настройки буфера пакетной записи ордеров
+type WriteBufferConfig struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled"`
+	BatchSize     int           `yaml:"batch_size" toml:"batch_size"`
+	BufferSize    int           `yaml:"buffer_size" toml:"buffer_size"`
+	FlushInterval time.Duration `yaml:"flush_interval" toml:"flush_interval"`
+	FlushTimeout  time.Duration `yaml:"flush_timeout" toml:"flush_timeout"`
+	// Время на запись накопленных ордеров при остановке сервиса; отсчитывается
+	// отдельно от server.shutdown_timeout, чтобы долгое ожидание запросов
+	// не лишило буфер времени на запись
+	DrainTimeout time.Duration `yaml:"drain_timeout" toml:"drain_timeout"`
+}
+
+// Настройки секционирования истории ордеров в PostgreSQL
+type PartitionsConfig struct {
+	Enabled         bool          `yaml:"enabled" toml:"enabled"`
+	Premake         int           `yaml:"premake" toml:"premake"`
+	RetentionMonths int           `yaml:"retention_months" toml:"retention_months"`
+	RetentionAction string        `yaml:"retention_action" toml:"retention_action"`
+	Interval        time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// Метод получения срока, в течение которого строки гарантированно остаются
+// в секциях: секция удаляется целиком не раньше, чем через RetentionMonths
+// календарных месяцев после её окончания, поэтому срок равен кратчайшему
+// промежутку из RetentionMonths подряд идущих календарных месяцев.
+// Ноль — секции не удаляются.
+func (c PartitionsConfig) minRetention() time.Duration {
+	if c.RetentionMonths <= 0 {
+		return 0
+	}
+	// Григорианский календарь повторяется каждые 400 лет, поэтому достаточно
+	// перебрать начала месяцев одного цикла
+	var shortest time.Duration
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 400*12; i++ {
+		from := start.AddDate(0, i, 0)
+		if d := from.AddDate(0, c.RetentionMonths, 0).Sub(from); shortest == 0 || d < shortest {
+			shortest = d
+		}
+	}
+	return shortest
+}
+
+// Функция проверки, что срок хранения retention (ноль — бессрочно)
+// не превышает limit
+func retentionWithin(retention, limit time.Duration) bool {
+	return retention > 0 && retention <= limit
+}
+
+// Настройки сроков хранения и архивации данных. Нулевой срок означает
+// бессрочное хранение. Clients переопределяет срок хранения истории ордеров
+// для отдельных клиентов в виде "клиент=срок".
+type RetentionConfig struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled"`
+	ArchiveDir   string        `yaml:"archive_dir" toml:"archive_dir"`
+	Interval     time.Duration `yaml:"interval" toml:"interval"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size"`
+	OrderBooks   time.Duration `yaml:"order_books" toml:"order_books"`
+	OrderHistory time.Duration `yaml:"order_history" toml:"order_history"`
+	Clients      []string      `yaml:"clients" toml:"clients"`
+}
+
+// Настройки проверок живости и готовности. WriteBufferHighWater — доля
+// заполнения буфера записи, начиная с которой сервис считается неготовым.
+type HealthConfig struct {
+	Timeout              time.Duration `yaml:"timeout" toml:"timeout"`
+	WriteBufferHighWater float64       `yaml:"write_buffer_high_water" toml:"write_buffer_high_water"`
+}
+
+// Настройки аутентификации. APIKeys — ключи API в виде имя=ключ; имя
+// владельца ключа попадает в журнал запросов (api_key). Пустой список
+// отключает проверку ключей. Из файла APIKeysFile ключи читаются по одному
+// на строку и заменяют APIKeys.
+type AuthConfig struct {
+	APIKeys     []string `yaml:"api_keys" toml:"api_keys"`
+	APIKeysFile string   `yaml:"api_keys_file" toml:"api_keys_file"`
+}
+
+// Настройки ограничения частоты запросов. Ограничения задаются отдельно для
+// чтения и записи: Rate — запросов в секунду на клиента, Burst — сколько
+// запросов клиент может выполнить подряд. CleanupInterval — период удаления
+// состояния клиентов, не исчерпавших ограничение.
+type RateLimitConfig struct {
+	Enabled         bool          `yaml:"enabled" toml:"enabled"`
+	ReadRate        float64       `yaml:"read_rate" toml:"read_rate"`
+	ReadBurst       int           `yaml:"read_burst" toml:"read_burst"`
+	WriteRate       float64       `yaml:"write_rate" toml:"write_rate"`
+	WriteBurst      int           `yaml:"write_burst" toml:"write_burst"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval"`
+}
+
+// Настройки логирования
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Экспортёры трассировки
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+	TracingOTLP   = "otlp"
+)
+
+// Настройки трассировки. File — файл для экспортёра file, Endpoint — адрес
+// OTLP/HTTP коллектора (host:port) для экспортёра otlp, SampleRatio — доля
+// записываемых трасс, если решение не принято вызывающим сервисом.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	File        string  `yaml:"file" toml:"file"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+}
+
+// Настройки меток бирж и пар валют в метриках. Значения меток приходят от
+// клиентов, поэтому их число ограничено: биржи и пары вне непустых списков
+// Exchanges и Pairs, а также сочетания сверх MaxPairs учитываются с меткой "other".
+type MetricsConfig struct {
+	Exchanges []string `yaml:"exchanges" toml:"exchanges"`
+	Pairs     []string `yaml:"pairs" toml:"pairs"`
+	MaxPairs  int      `yaml:"max_pairs" toml:"max_pairs"`
+}
+
+// Переключатели функциональности
+type FeaturesConfig struct {
+	Swagger     bool `yaml:"swagger" toml:"swagger"`
+	SchemaCheck bool `yaml:"schema_check" toml:"schema_check"`
+	Metrics     bool `yaml:"metrics" toml:"metrics"`
+	Compression bool `yaml:"compression" toml:"compression"`
+}
+
+// Функция, возвращающая конфигурацию по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Addr:       ":9090",
+			Reflection: true,
+		},
+		Storage: StorageConfig{
+			Driver:            StoragePostgres,
+			SQLitePath:        "stats.db",
+			SQLiteBusyTimeout: 5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 "postgres",
+			Name:                 "stats-collection",
+			SSLMode:              "disable",
+			MaxOpenConns:         25,
+			MaxIdleConns:         25,
+			ConnMaxLifetime:      5 * time.Minute,
+			ConnectTimeout:       5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		Timeouts: TimeoutsConfig{
+			Default: 5 * time.Second,
+		},
+		Cache: CacheConfig{
+			Size: 1024,
+			TTL:  2 * time.Second,
+		},
+		WriteBuffer: WriteBufferConfig{
+			BatchSize:     500,
+			BufferSize:    10000,
+			FlushInterval: 5 * time.Millisecond,
+			FlushTimeout:  5 * time.Second,
+			DrainTimeout:  10 * time.Second,
+		},
+		Partitions: PartitionsConfig{
+			Premake:         3,
+			RetentionAction: "drop",
+			Interval:        time.Hour,
+		},
+		Retention: RetentionConfig{
+			ArchiveDir:   "archive",
+			Interval:     time.Hour,
+			BatchSize:    1000,
+			OrderBooks:   30 * 24 * time.Hour,
+			OrderHistory: 7 * 365 * 24 * time.Hour,
+		},
+		Health: HealthConfig{
+			Timeout:              time.Second,
+			WriteBufferHighWater: 0.9,
+		},
+		RateLimit: RateLimitConfig{
+			ReadRate:        50,
+			ReadBurst:       100,
+			WriteRate:       20,
+			WriteBurst:      40,
+			CleanupInterval: time.Minute,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			File:        "traces.json",
+			SampleRatio: 1,
+			ServiceName: "statistics-collection-service",
+		},
+		Metrics: MetricsConfig{
+			MaxPairs: 1000,
+		},
+		Features: FeaturesConfig{
+			Swagger:     true,
+			SchemaCheck: true,
+			Metrics:     true,
+			Compression: true,
+		},
+	}
+}
+
+// Функция загрузки конфигурации. Источники применяются в порядке возрастания
+// приоритета: значения по умолчанию, файл, переменные окружения, флаги командной строки.
+// Возвращает аргументы, оставшиеся после разбора флагов.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("statistics-collection-service", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "path to a YAML or TOML config file (env "+EnvPrefix+"CONFIG)")
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		name := f.flagName()
+		usage := "(env " + f.envName() + ")"
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(name, usage, func(s string) error {
+				flagValues[name] = s
+				return nil
+			})
+			continue
+		}
+		fs.Func(name, usage, func(s string) error {
+			flagValues[name] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(cfg, *configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if s, ok := os.LookupEnv(f.envName()); ok {
+			if err := setValue(f.value, s); err != nil {
+				return nil, nil, fmt.Errorf("env %s: %w", f.envName(), err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if s, ok := flagValues[f.flagName()]; ok {
+			if err := setValue(f.value, s); err != nil {
+				return nil, nil, fmt.Errorf("flag -%s: %w", f.flagName(), err)
+			}
+		}
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// Метод для проверки корректности конфигурации
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr: %w", err))
+	}
+	if c.GRPC.Enabled {
+		if _, port, err := net.SplitHostPort(c.GRPC.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("grpc.addr: %w", err))
+		} else if c.GRPC.Addr == c.Server.Addr && port != "0" {
+			errs = append(errs, errors.New("grpc.addr must differ from server.addr"))
+		}
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"database.connect_timeout", c.Database.ConnectTimeout},
+		{"timeouts.default", c.Timeouts.Default},
+		{"health.timeout", c.Health.Timeout},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", d.name))
+		}
+	}
+
+	switch c.Storage.Driver {
+	case StoragePostgres, StorageMemory:
+	case StorageSQLite:
+		if c.Storage.SQLitePath == "" {
+			errs = append(errs, errors.New("storage.sqlite_path is required for the sqlite driver"))
+		}
+		if c.Storage.SQLiteBusyTimeout < 0 {
+			errs = append(errs, errors.New("storage.sqlite_busy_timeout must not be negative"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver %q must be postgres, sqlite or memory", c.Storage.Driver))
+	}
+
+	if c.Storage.Driver == StoragePostgres && c.Database.DSN == "" {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database pool sizes must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns must not exceed database.max_open_conns"))
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.conn_max_lifetime must not be negative"))
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeouts.get_order_book", c.Timeouts.GetOrderBook},
+		{"timeouts.save_order_book", c.Timeouts.SaveOrderBook},
+		{"timeouts.get_order_history", c.Timeouts.GetOrderHistory},
+		{"timeouts.save_order", c.Timeouts.SaveOrder},
+	} {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", d.name))
+		}
+	}
+
+	if c.Cache.Enabled && c.Cache.Size <= 0 {
+		errs = append(errs, errors.New("cache.size must be positive when the cache is enabled"))
+	}
+	if c.Cache.TTL < 0 {
+		errs = append(errs, errors.New("cache.ttl must not be negative"))
+	}
+
+	if c.WriteBuffer.Enabled {
+		if c.WriteBuffer.BatchSize <= 0 {
+			errs = append(errs, errors.New("write_buffer.batch_size must be positive when the write buffer is enabled"))
+		}
+		if c.WriteBuffer.BufferSize < 0 {
+			errs = append(errs, errors.New("write_buffer.buffer_size must not be negative"))
+		}
+		if c.WriteBuffer.FlushInterval <= 0 {
+			errs = append(errs, errors.New("write_buffer.flush_interval must be positive when the write buffer is enabled"))
+		}
+		if c.WriteBuffer.DrainTimeout <= 0 {
+			errs = append(errs, errors.New("write_buffer.drain_timeout must be positive when the write buffer is enabled"))
+		}
+	}
+	if c.WriteBuffer.FlushTimeout < 0 {
+		errs = append(errs, errors.New("write_buffer.flush_timeout must not be negative"))
+	}
+
+	if c.Partitions.Premake < 0 {
+		errs = append(errs, errors.New("partitions.premake must not be negative"))
+	}
+	if c.Partitions.RetentionMonths < 0 {
+		errs = append(errs, errors.New("partitions.retention_months must not be negative"))
+	}
+	switch c.Partitions.RetentionAction {
+	case "drop", "detach":
+	default:
+		errs = append(errs, fmt.Errorf("partitions.retention_action %q must be drop or detach", c.Partitions.RetentionAction))
+	}
+	if c.Partitions.Enabled && c.Partitions.Interval <= 0 {
+		errs = append(errs, errors.New("partitions.interval must be positive when partition management is enabled"))
+	}
+
+	if c.Retention.Enabled {
+		if c.Retention.ArchiveDir == "" {
+			errs = append(errs, errors.New("retention.archive_dir is required when retention is enabled"))
+		}
+		if c.Retention.Interval <= 0 {
+			errs = append(errs, errors.New("retention.interval must be positive when retention is enabled"))
+		}
+		if c.Retention.BatchSize <= 0 {
+			errs = append(errs, errors.New("retention.batch_size must be positive when retention is enabled"))
+		}
+	}
+	if c.Retention.OrderBooks < 0 || c.Retention.OrderHistory < 0 {
+		errs = append(errs, errors.New("retention periods must not be negative"))
+	}
+	// Удаляемые секции истории не архивируются и не учитывают сроки хранения
+	// отдельных клиентов, поэтому при архивации ни один срок не может
+	// превышать срок хранения секций. Отсоединённые секции остаются в базе.
+	var partitionsKeep time.Duration
+	if c.Retention.Enabled && c.Partitions.Enabled && c.Partitions.RetentionAction == partitions.ActionDrop {
+		partitionsKeep = c.Partitions.minRetention()
+	}
+	if partitionsKeep > 0 && !retentionWithin(c.Retention.OrderHistory, partitionsKeep) {
+		errs = append(errs, errors.New("retention.order_history must not exceed partitions.retention_months, or partitions would drop orders before they are archived"))
+	}
+	for _, entry := range c.Retention.Clients {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			errs = append(errs, fmt.Errorf("retention.clients entry %q must have the form client=duration", entry))
+		} else if d, err := time.ParseDuration(entry[i+1:]); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("retention.clients entry %q has an invalid duration", entry))
+		} else if partitionsKeep > 0 && !retentionWithin(d, partitionsKeep) {
+			errs = append(errs, fmt.Errorf("retention.clients entry %q must not exceed partitions.retention_months, or partitions would drop the client's orders", entry))
+		}
+	}
+
+	for i, entry := range c.Auth.APIKeys {
+		if name, key, ok := strings.Cut(entry, "="); !ok || name == "" || key == "" {
+			errs = append(errs, fmt.Errorf("auth.api_keys entry %d must have the form name=key", i+1))
+		}
+	}
+
+	if c.Features.Metrics && c.Metrics.MaxPairs <= 0 {
+		errs = append(errs, errors.New("metrics.max_pairs must be positive when metrics are enabled"))
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.ReadRate <= 0 || c.RateLimit.WriteRate <= 0 {
+			errs = append(errs, errors.New("rate_limit.read_rate and rate_limit.write_rate must be positive when rate limiting is enabled"))
+		}
+		if c.RateLimit.ReadBurst < 1 || c.RateLimit.WriteBurst < 1 {
+			errs = append(errs, errors.New("rate_limit.read_burst and rate_limit.write_burst must be at least 1 when rate limiting is enabled"))
+		}
+		if c.RateLimit.CleanupInterval <= 0 {
+			errs = append(errs, errors.New("rate_limit.cleanup_interval must be positive when rate limiting is enabled"))
+		}
+	}
+
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("server.drain_delay must not be negative"))
+	} else if c.Server.DrainDelay >= c.Server.ShutdownTimeout {
+		errs = append(errs, errors.New("server.drain_delay must be less than server.shutdown_timeout"))
+	}
+	if c.Health.WriteBufferHighWater <= 0 || c.Health.WriteBufferHighWater > 1 {
+		errs = append(errs, errors.New("health.write_buffer_high_water must be in (0, 1]"))
+	}
+
+	if len(c.Database.Replicas) > 0 && c.Database.ReplicaCheckInterval <= 0 {
+		errs = append(errs, errors.New("database.replica_check_interval must be positive when replicas are configured"))
+	}
+
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("logging.level %q must be one of debug, info, warn, error", c.Logging.Level))
+	}
+	switch c.Logging.Format {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("logging.format %q must be text or json", c.Logging.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingFile:
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file is required for the file exporter"))
+		}
+	case TracingOTLP:
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required for the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q must be one of none, stdout, file, otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be in [0, 1]"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Метод для получения строки подключения к базе данных
+func (c DatabaseConfig) ConnString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:   "/" + c.Name,
+	}
+	query := url.Values{}
+	if c.SSLMode != "" {
+		query.Set("sslmode", c.SSLMode)
+	}
+	if c.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(c.ConnectTimeout.Seconds())))
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Метод для чтения секретов из файлов
+func (c *Config) resolveSecrets() error {
+	if c.Database.PasswordFile != "" {
+		secret, err := readSecret(c.Database.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("database.password_file: %w", err)
+		}
+		c.Database.Password = secret
+	}
+	if c.Database.ReplicasFile != "" {
+		replicas, err := readSecretList(c.Database.ReplicasFile)
+		if err != nil {
+			return fmt.Errorf("database.replicas_file: %w", err)
+		}
+		c.Database.Replicas = replicas
+	}
+	if c.Auth.APIKeysFile != "" {
+		keys, err := readSecretList(c.Auth.APIKeysFile)
+		if err != nil {
+			return fmt.Errorf("auth.api_keys_file: %w", err)
+		}
+		c.Auth.APIKeys = keys
+	}
+	return nil
+}
+
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Функция чтения списка секретов из файла: по одному на строку, пустые
+// строки и строки, начинающиеся с #, пропускаются
+func readSecretList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse config file %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	return nil
+}
+
+// Поле конфигурации, доступное для переопределения через окружение и флаги
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+func (f field) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.key, ".", "_"))
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(fv, key+".")...)
+			continue
+		}
+		fields = append(fields, field{key: key, value: fv})
+	}
+	return fields
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, args, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  read_timeout: 3s
+database:
+  host: db.internal
+  max_open_conns: 50
+  max_idle_conns: 10
+logging:
+  level: debug
+`)
+	t.Setenv("STATS_CONFIG", path)
+	t.Setenv("STATS_DATABASE_HOST", "db.env")
+	t.Setenv("STATS_LOGGING_LEVEL", "warn")
+
+	cfg, args, err := Load([]string{"-logging.level", "error", "-features.swagger=false", "migrate", "up"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 10*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, "db.env", cfg.Database.Host)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, "error", cfg.Logging.Level)
+	assert.False(t, cfg.Features.Swagger)
+	assert.True(t, cfg.Features.SchemaCheck)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[server]
+addr = "127.0.0.1:8081"
+shutdown_timeout = "30s"
+
+[database]
+name = "stats"
+`)
+
+	cfg, _, err := Load([]string{"-config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8081", cfg.Server.Addr)
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "stats", cfg.Database.Name)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  adr: \":9000\"\n")
+
+	_, _, err := Load([]string{"-config", path})
+	assert.Error(t, err)
+}
+
+func TestLoad_PasswordFile(t *testing.T) {
+	secret := writeFile(t, "password", "s3cr3t\n")
+	t.Setenv("STATS_DATABASE_PASSWORD_FILE", secret)
+
+	cfg, _, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", cfg.Database.Password)
+	assert.Contains(t, cfg.Database.ConnString(), "postgres:s3cr3t@localhost:5432/stats-collection")
+}
+
+func TestLoad_SecretListFiles(t *testing.T) {
+	keys := writeFile(t, "api_keys", "# collectors\ncollector-1=abc\n\n  collector-2=def  \n")
+	replicas := writeFile(t, "replicas", "postgres://u:p@replica1/db\npostgres://u:p@replica2/db\n")
+	t.Setenv("STATS_AUTH_API_KEYS", "ignored=xyz")
+	t.Setenv("STATS_AUTH_API_KEYS_FILE", keys)
+	t.Setenv("STATS_DATABASE_REPLICAS_FILE", replicas)
+
+	cfg, _, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"collector-1=abc", "collector-2=def"}, cfg.Auth.APIKeys)
+	assert.Equal(t, []string{"postgres://u:p@replica1/db", "postgres://u:p@replica2/db"}, cfg.Database.Replicas)
+
+	t.Setenv("STATS_AUTH_API_KEYS_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, _, err = Load(nil)
+	assert.ErrorContains(t, err, "auth.api_keys_file")
+}
+
+func TestLoad_InvalidValue(t *testing.T) {
+	t.Setenv("STATS_DATABASE_PORT", "not-a-number")
+
+	_, _, err := Load(nil)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = "8080"
+	cfg.Database.MaxIdleConns = 100
+	cfg.Logging.Format = "xml"
+	cfg.Tracing.Exporter = TracingOTLP
+	cfg.Auth.APIKeys = []string{"collector-1=abc", "secret"}
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.WriteBurst = 0
+	cfg.GRPC.Enabled = true
+	cfg.GRPC.Addr = "9090"
+	cfg.Retention.Clients = []string{"John Doe=720h", "Jane Doe"}
+	cfg.Metrics.MaxPairs = 0
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "server.addr")
+	assert.ErrorContains(t, err, `retention.clients entry "Jane Doe"`)
+	assert.NotContains(t, err.Error(), "John Doe")
+	assert.ErrorContains(t, err, "max_idle_conns")
+	assert.ErrorContains(t, err, "logging.format")
+	assert.ErrorContains(t, err, "tracing.endpoint")
+	assert.ErrorContains(t, err, "auth.api_keys entry 2")
+	assert.NotContains(t, err.Error(), "secret")
+	assert.ErrorContains(t, err, "rate_limit.read_burst and rate_limit.write_burst")
+	assert.NotContains(t, err.Error(), "rate_limit.read_rate")
+	assert.ErrorContains(t, err, "grpc.addr")
+	assert.ErrorContains(t, err, "metrics.max_pairs")
+}
+
+func TestValidate_PartitionRetention(t *testing.T) {
+	dropping := func() *Config {
+		cfg := Default()
+		cfg.Retention.Enabled = true
+		cfg.Partitions.Enabled = true
+		cfg.Partitions.RetentionMonths = 12
+		cfg.Partitions.RetentionAction = "drop"
+		return cfg
+	}
+
+	cfg := dropping()
+	cfg.Retention.OrderHistory = 300 * 24 * time.Hour
+	cfg.Retention.Clients = []string{"John Doe=2160h", "Jane Doe=9000h", "VIP=0s"}
+	err := cfg.Validate()
+	assert.NotContains(t, err.Error(), "retention.order_history")
+	assert.NotContains(t, err.Error(), "John Doe")
+	assert.ErrorContains(t, err, `retention.clients entry "Jane Doe=9000h" must not exceed partitions.retention_months`)
+	assert.ErrorContains(t, err, `retention.clients entry "VIP=0s" must not exceed partitions.retention_months`)
+
+	// Год из 12 календарных месяцев не короче 365 дней
+	cfg.Retention.Clients = []string{"Jane Doe=8760h"}
+	assert.NoError(t, cfg.Validate())
+
+	// Бессрочное хранение истории несовместимо с удалением секций
+	cfg.Retention.OrderHistory = 0
+	assert.ErrorContains(t, cfg.Validate(), "retention.order_history must not exceed partitions.retention_months")
+
+	// Срок по умолчанию в 7 лет укладывается в 84 календарных месяца
+	cfg = dropping()
+	cfg.Partitions.RetentionMonths = 84
+	assert.NoError(t, cfg.Validate())
+	cfg.Partitions.RetentionMonths = 83
+	assert.ErrorContains(t, cfg.Validate(), "retention.order_history must not exceed partitions.retention_months")
+
+	// Сроки не ограничены, если секции не удаляются или архивация выключена
+	for name, modify := range map[string]func(*Config){
+		"retention disabled":  func(c *Config) { c.Retention.Enabled = false },
+		"partitions disabled": func(c *Config) { c.Partitions.Enabled = false },
+		"detach":              func(c *Config) { c.Partitions.RetentionAction = "detach" },
+		"no retention months": func(c *Config) { c.Partitions.RetentionMonths = 0 },
+	} {
+		cfg := dropping()
+		cfg.Retention.OrderHistory = 0
+		cfg.Retention.Clients = []string{"VIP=0s"}
+		modify(cfg)
+		assert.NoError(t, cfg.Validate(), name)
+	}
+}
+
+func TestTimeoutsConfig_For(t *testing.T) {
+	cfg := TimeoutsConfig{Default: 5 * time.Second, GetOrderHistory: 20 * time.Second}
+	assert.Equal(t, 5*time.Second, cfg.For(cfg.GetOrderBook))
+	assert.Equal(t, 20*time.Second, cfg.For(cfg.GetOrderHistory))
+}
+
+func TestDatabaseConfig_ConnString(t *testing.T) {
+	cfg := Default().Database
+	cfg.DSN = "postgres://user@host/db"
+	assert.Equal(t, "postgres://user@host/db", cfg.ConnString())
+}
@@ -16,9 +16,22 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/order/save": {
+        "/api/v1/order/save": {
             "post": {
-                "description": "Сохранить новый ордер для указанного клиента",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Сохранить новый ордер для указанного клиента.\nТело принимается в JSON, MessagePack или Protobuf (HistoryOrder) по заголовку Content-Type.",
+                "consumes": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/problem+json"
+                ],
                 "summary": "Сохранить ордер",
                 "parameters": [
                     {
@@ -41,21 +54,74 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Требуется ключ API",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат тела запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибка проверки данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышено ограничение частоты запросов",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время обработки запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orderbook/get": {
+        "/api/v1/orderbook/get": {
             "get": {
-                "description": "Получить книгу ордеров для указанной биржи и пары валют",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получить книгу ордеров для указанной биржи и пары валют.\nФормат ответа выбирается по заголовку Accept: JSON, MessagePack или Protobuf (GetOrderBookResponse).",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf",
+                    "application/problem+json"
+                ],
                 "summary": "Получить книгу ордеров",
                 "parameters": [
                     {
@@ -71,6 +137,18 @@ const docTemplate = `{
                         "name": "pair",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag имеющейся у клиента версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Время последнего полученного изменения",
+                        "name": "If-Modified-Since",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -81,20 +159,88 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/models.DepthOrder"
                             }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия книги ордеров"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Время сохранения книги ордеров"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Книга ордеров не изменилась"
+                    },
+                    "401": {
+                        "description": "Требуется ключ API",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Книга ордеров не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Неподдерживаемый формат ответа",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибка проверки данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышено ограничение частоты запросов",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время обработки запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orderbook/save": {
+        "/api/v1/orderbook/save": {
             "post": {
-                "description": "Сохранить книгу ордеров для указанной биржи и пары валют",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Сохранить книгу ордеров для указанной биржи и пары валют.\nТело принимается в JSON, MessagePack или Protobuf (SaveOrderBookRequest) по заголовку Content-Type.",
+                "consumes": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/problem+json"
+                ],
                 "summary": "Сохранить книгу ордеров",
                 "parameters": [
                     {
@@ -105,6 +251,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.OrderBook"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag версии, которую необходимо заменить, или *",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -112,26 +264,97 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия сохранённой книги ордеров"
+                            }
                         }
                     },
                     "400": {
                         "description": "Некорректный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Требуется ключ API",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия книги ордеров изменилась",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат тела запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибка проверки данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышено ограничение частоты запросов",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время обработки запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orderhistory/get": {
+        "/api/v1/orderhistory/get": {
             "get": {
-                "description": "Получить историю ордеров для указанного клиента",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получить историю ордеров для указанного клиента.\nТело принимается в JSON, MessagePack или Protobuf (Client); ответ — в JSON, MessagePack,\nProtobuf (GetOrderHistoryResponse) или CSV по заголовку Accept.",
+                "consumes": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/x-protobuf",
+                    "text/csv",
+                    "application/problem+json"
+                ],
                 "summary": "Получить историю ордеров",
                 "parameters": [
                     {
@@ -157,13 +380,101 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Требуется ключ API",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Неподдерживаемый формат ответа",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат тела запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибка проверки данных",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышено ограничение частоты запросов",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Превышено время обработки запроса",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Сервис работает и не требует перезапуска",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Проверка живости",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Сервис готов принимать запросы: база данных доступна, схема актуальна,\nбуфер записи не переполнен, сервис не останавливается",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
                         }
                     }
                 }
@@ -171,6 +482,81 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "api.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Описание конкретного случая",
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Ошибки проверки полей",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.FieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Путь запроса",
+                    "type": "string",
+                    "example": "/api/v1/order/save"
+                },
+                "request_id": {
+                    "description": "Идентификатор запроса (X-Request-ID)",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP-код ответа",
+                    "type": "integer",
+                    "example": 422
+                },
+                "title": {
+                    "description": "Краткое описание типа ошибки",
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "type": {
+                    "description": "URI типа ошибки",
+                    "type": "string",
+                    "example": "/problems/validation-failed"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "version 3"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number",
+                    "example": 0.42
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
         "models.Client": {
             "type": "object",
             "properties": {
@@ -185,6 +571,13 @@ const docTemplate = `{
                 },
                 "pair": {
                     "type": "string"
+                },
+                "time_from": {
+                    "description": "Необязательные границы периода истории ордеров: [TimeFrom, TimeTo)",
+                    "type": "string"
+                },
+                "time_to": {
+                    "type": "string"
                 }
             }
         },
@@ -217,13 +610,13 @@ const docTemplate = `{
                 "exchange_name": {
                     "type": "string"
                 },
-                "highest_buy_price": {
+                "highest_buy_prc": {
                     "type": "number"
                 },
                 "label": {
                     "type": "string"
                 },
-                "lowest_sell_price": {
+                "lowest_sell_prc": {
                     "type": "number"
                 },
                 "pair": {
@@ -268,6 +661,24 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "services.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
         }
     }
 }`
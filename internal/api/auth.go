@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Заголовок, в котором передаётся ключ API. Ключ можно передать и в
+// заголовке Authorization: Bearer <ключ>.
+const APIKeyHeader = "X-API-Key"
+
+type keyNameKey struct{}
+
+// Функция для получения имени владельца ключа API, которым подписан запрос
+func KeyNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(keyNameKey{}).(string)
+	return name
+}
+
+// Функция для сохранения имени владельца ключа API в контексте
+func WithKeyName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, keyNameKey{}, name)
+}
+
+type keyNameHolderKey struct{}
+
+// Функция для подготовки контекста, в который Authenticate запишет имя
+// владельца ключа. Нужна middleware, работающим до проверки ключа (журнал
+// запросов): возвращённая функция после обработки запроса возвращает имя
+// владельца или пустую строку.
+func WithKeyNameHolder(ctx context.Context) (context.Context, func() string) {
+	holder := new(string)
+	return context.WithValue(ctx, keyNameHolderKey{}, holder), func() string { return *holder }
+}
+
+// Ключи API: имя владельца по ключу
+type APIKeys map[string]string
+
+// Функция разбора ключей API из записей вида имя=ключ
+func ParseAPIKeys(entries []string) (APIKeys, error) {
+	keys := make(APIKeys, len(entries))
+	for n, entry := range entries {
+		// Сама запись в ошибку не попадает, чтобы ключ не оказался в журнале
+		i := strings.Index(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("api key entry %d must have the form name=key", n+1)
+		}
+		keys[entry[i+1:]] = entry[:i]
+	}
+	return keys, nil
+}
+
+// Метод поиска владельца ключа. Ключ сравнивается со всеми известными за
+// постоянное время, чтобы время ответа не подсказывало совпавший префикс.
+func (k APIKeys) Lookup(key string) (string, bool) {
+	var owner string
+	found := false
+	for candidate, name := range k {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			owner, found = name, true
+		}
+	}
+	return owner, found
+}
+
+// Функция получения ключа API из заголовков запроса
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Middleware проверки ключа API. Имя владельца действительного ключа
+// сохраняется в контексте запроса и передаётся в WithKeyNameHolder.
+// Маршруты с Auth без действительного ключа получают 401. Если ключи
+// не настроены, проверка отключена.
+func Authenticate(keys APIKeys) Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if name, ok := keys.Lookup(apiKey(r)); ok {
+				if holder, ok := r.Context().Value(keyNameHolderKey{}).(*string); ok {
+					*holder = name
+				}
+				r = r.WithContext(WithKeyName(r.Context(), name))
+			} else if route := RouteFromContext(r.Context()); route != nil && route.Auth {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				problem := newProblem(http.StatusUnauthorized, "unauthorized", "Unauthorized",
+					"A valid API key is required in the "+APIKeyHeader+" header.")
+				problem.Instance = r.URL.Path
+				writeProblem(w, r, problem)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"collector-1=abc", "dashboard=x=y"})
+	require.NoError(t, err)
+	assert.Equal(t, APIKeys{"abc": "collector-1", "x=y": "dashboard"}, keys)
+
+	for _, entry := range []string{"secret", "=secret", "collector-1="} {
+		_, err := ParseAPIKeys([]string{entry})
+		require.Error(t, err, entry)
+		assert.NotContains(t, err.Error(), "secret")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	router := newTestRouter()
+	var owner string
+	router.Use(Authenticate(APIKeys{"abc": "collector-1"}), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			owner = KeyNameFromContext(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	request := func(method, path string, header ...string) *httptest.ResponseRecorder {
+		owner = ""
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/v1/orderbook/get")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	w = request(http.MethodGet, "/api/v1/orderbook/get", APIKeyHeader, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = request(http.MethodGet, "/api/v1/orderbook/get", APIKeyHeader, "abc")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "collector-1", owner)
+
+	w = request(http.MethodGet, "/orderbook/get", "Authorization", "Bearer abc")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "collector-1", owner)
+
+	// Маршрут без Auth доступен и без ключа, но действительный ключ учитывается
+	w = request(http.MethodPost, "/api/v1/orderbook/save")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, owner)
+	w = request(http.MethodPost, "/api/v1/orderbook/save", APIKeyHeader, "abc")
+	assert.Equal(t, "collector-1", owner)
+}
+
+func TestAuthenticate_Disabled(t *testing.T) {
+	router := newTestRouter()
+	router.Use(Authenticate(nil))
+	w := serve(router, http.MethodGet, "/api/v1/orderbook/get")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Ответы меньше этого размера не сжимаются: выигрыш меньше накладных расходов
+const compressMinSize = 1024
+
+// Поддерживаемые алгоритмы сжатия в порядке предпочтения сервера
+var compressEncodings = []string{"zstd", "gzip"}
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+var zstdPool = sync.Pool{New: func() any {
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	return encoder
+}}
+
+// Функция для выбора алгоритма сжатия по заголовку Accept-Encoding; пустая
+// строка — ответ передаётся без сжатия
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, encoding := range compressEncodings {
+		q, specific := 0.0, false
+		for _, item := range parseQList(header) {
+			switch {
+			case item.value == encoding:
+				q, specific = item.q, true
+			case item.value == "*" && !specific:
+				q = item.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// Middleware сжатия ответов gzip или zstd по заголовку Accept-Encoding.
+// Ответы, уже имеющие Content-Encoding, ответы без тела и короткие ответы
+// передаются как есть.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Обёртка над http.ResponseWriter, накапливающая начало ответа, пока не
+// станет ясно, нужно ли его сжимать
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	status      int
+	wroteHeader bool
+	// Решение принято: ответ сжимается (encoder != nil) или передаётся как есть
+	decided bool
+	encoder io.WriteCloser
+	buf     []byte
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified || cw.Header().Get("Content-Encoding") != "" {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Метод для передачи ответа без сжатия
+func (cw *compressWriter) passThrough() {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Метод для начала сжатия и записи накопленного начала ответа
+func (cw *compressWriter) startCompression() error {
+	cw.decided = true
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	// Сжатое представление отличается от исходного побайтно, поэтому его ETag
+	// становится слабым
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	switch cw.encoding {
+	case "zstd":
+		encoder := zstdPool.Get().(*zstd.Encoder)
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	default:
+		encoder := gzipPool.Get().(*gzip.Writer)
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	}
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.encoder.Write(buf)
+	return err
+}
+
+// Метод завершения ответа: короткий ответ отправляется без сжатия, сжатый
+// поток дописывается, а кодировщик возвращается в пул
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// Обработчик ничего не записал — ответ отправит http.Server
+			return
+		}
+		cw.passThrough()
+		if len(cw.buf) > 0 {
+			cw.ResponseWriter.Write(cw.buf)
+		}
+		return
+	}
+	if cw.encoder == nil {
+		return
+	}
+	cw.encoder.Close()
+	switch encoder := cw.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		zstdPool.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		gzipPool.Put(encoder)
+	}
+	cw.encoder = nil
+}
+
+// Метод для промежуточной отправки данных клиенту: накопленное начало
+// ответа сжимается, не дожидаясь порога
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		if len(cw.buf) == 0 {
+			cw.passThrough()
+		} else if cw.startCompression() != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Метод для доступа к исходному http.ResponseWriter через http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
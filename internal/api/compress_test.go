@@ -0,0 +1,84 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, expected := range map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br, zstd": "zstd",
+		"zstd;q=0.5, gzip":        "gzip",
+		"*":                       "zstd",
+		"*, zstd;q=0":             "gzip",
+		"gzip;q=0":                "",
+	} {
+		assert.Equal(t, expected, negotiateEncoding(header), header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"price":50000,"base_qty":0.1},`, 100)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte(large))
+		case "/small":
+			w.Write([]byte("{}"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("/large", "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, `W/"1"`, w.Header().Get("ETag"))
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	w = request("/large", "zstd")
+	assert.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+	decoder, err := zstd.NewReader(w.Body)
+	require.NoError(t, err)
+	defer decoder.Close()
+	body, err = io.ReadAll(decoder)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	w = request("/large", "")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = request("/small", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "{}", w.Body.String())
+
+	w = request("/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/repository"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Суффиксы ETag форматов ответа: представления одной версии в разных
+// форматах должны иметь разные ETag
+var etagSuffixes = map[string]string{
+	ContentTypeMsgPack:  "-msgpack",
+	ContentTypeProtobuf: "-protobuf",
+	ContentTypeCSV:      "-csv",
+}
+
+// Функция построения ETag представления версии данных в формате contentType
+func entityTag(version int64, contentType string) string {
+	return `"` + strconv.FormatInt(version, 10) + etagSuffixes[contentType] + `"`
+}
+
+// Функция разбора списка ETag заголовков If-Match и If-None-Match. Признак
+// слабого ETag отбрасывается; "*" возвращается как есть.
+func parseEntityTags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Функция проверки условий If-None-Match и If-Modified-Since (RFC 9110,
+// раздел 13.2.2) для ответа с ETag etag и временем изменения modified. Возвращает
+// true, если у клиента уже есть актуальное представление и достаточно ответа 304.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		// Сравнение слабое: сжатое представление имеет слабый ETag
+		for _, tag := range parseEntityTags(header) {
+			if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		// If-Modified-Since игнорируется, если передан If-None-Match
+		return false
+	}
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified передаётся с точностью до секунды
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// Функция разбора заголовка If-Match для условной записи. Возвращает версию
+// из ETag в любом из форматов ответа, repository.AnyVersion для "*" или 0, если
+// заголовок не передан.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+	tags := parseEntityTags(header)
+	if len(tags) != 1 {
+		return 0, badRequest(errors.New("If-Match must contain a single entity tag"))
+	}
+	if tags[0] == "*" {
+		return repository.AnyVersion, nil
+	}
+	tag := strings.Trim(tags[0], `"`)
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		tag = tag[:i]
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, badRequest(fmt.Errorf("If-Match %s is not an entity tag issued by this service", tags[0]))
+	}
+	return version, nil
+}
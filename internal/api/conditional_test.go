@@ -0,0 +1,118 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrderBookHandler_ConditionalGet(t *testing.T) {
+	service := services.NewService(repository.NewInMemoryRepository())
+	_, err := service.SaveOrderBookSnapshot(context.Background(), "binance", "BTC/USDT", nil, 0)
+	require.NoError(t, err)
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orderbook/get?exchange_name=binance&pair=BTC/USDT", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		GetOrderBookHandler(service).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+	lastModified := rr.Header().Get("Last-Modified")
+	modified, err := http.ParseTime(lastModified)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), modified, 5*time.Second)
+
+	rr = get(map[string]string{"If-None-Match": `"1"`})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+	// Слабое сравнение: ETag сжатого ответа тоже подходит
+	rr = get(map[string]string{"If-None-Match": `"0", W/"1"`})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	// У представления в другом формате свой ETag
+	rr = get(map[string]string{"If-None-Match": `"1"`, "Accept": ContentTypeMsgPack})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"1-msgpack"`, rr.Header().Get("ETag"))
+
+	rr = get(map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	rr = get(map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// If-Modified-Since не учитывается, если передан If-None-Match
+	rr = get(map[string]string{"If-None-Match": `"0"`, "If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	_, err = service.SaveOrderBookSnapshot(context.Background(), "binance", "BTC/USDT", nil, 0)
+	require.NoError(t, err)
+	rr = get(map[string]string{"If-None-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+}
+
+func TestSaveOrderBookHandler_IfMatch(t *testing.T) {
+	service := services.NewService(repository.NewInMemoryRepository())
+
+	save := func(pair, ifMatch string) *httptest.ResponseRecorder {
+		body := `{"exchange_name":"binance","pair":"` + pair + `","order_book":[{"price":1,"base_qty":1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/orderbook/save", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		SaveOrderBookHandler(service).ServeHTTP(rr, req)
+		return rr
+	}
+	problemType := func(rr *httptest.ResponseRecorder) string {
+		var problem Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+		return problem.Type
+	}
+
+	rr := save("BTC/USDT", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+	rr = save("BTC/USDT", `"1-msgpack"`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+	// Книга уже изменена другим клиентом
+	rr = save("BTC/USDT", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, "/problems/precondition-failed", problemType(rr))
+
+	rr = save("BTC/USDT", "*")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+	rr = save("ETH/USDT", "*")
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+	for _, ifMatch := range []string{`"abc"`, `"1", "2"`, `"0"`} {
+		rr = save("BTC/USDT", ifMatch)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, ifMatch)
+		assert.Equal(t, "/problems/bad-request", problemType(rr))
+	}
+
+	snapshot, err := service.GetOrderBookSnapshot(context.Background(), "binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), snapshot.Version)
+}
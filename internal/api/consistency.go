@@ -0,0 +1,22 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/db"
+	"net/http"
+	"strconv"
+)
+
+// Заголовок, которым клиент запрашивает чтение с основного сервера базы данных,
+// чтобы сразу увидеть только что сохранённые данные
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// Middleware, направляющее чтения запроса на основной сервер базы данных,
+// если клиент передал заголовок X-Read-Your-Writes: true
+func ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enabled, _ := strconv.ParseBool(r.Header.Get(ReadYourWritesHeader)); enabled {
+			r = r.WithContext(db.WithPrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
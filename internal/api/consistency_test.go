@@ -0,0 +1,26 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/db"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	for header, expected := range map[string]bool{"": false, "true": true, "1": true, "false": false, "yes": false} {
+		var primary bool
+		handler := ReadYourWrites(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			primary = db.PrimaryRequested(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/orderhistory/get", nil)
+		if header != "" {
+			req.Header.Set(ReadYourWritesHeader, header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, expected, primary, "header %q", header)
+	}
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Поддерживаемые форматы тел запросов и ответов
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeMsgPack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeCSV      = "text/csv"
+)
+
+// Распространённые альтернативные имена форматов
+var mediaTypeAliases = map[string]string{
+	"application/x-msgpack":           ContentTypeMsgPack,
+	"application/vnd.msgpack":         ContentTypeMsgPack,
+	"application/protobuf":            ContentTypeProtobuf,
+	"application/vnd.google.protobuf": ContentTypeProtobuf,
+}
+
+// Ошибка неподдерживаемого формата тела запроса
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// Элемент списка заголовка Accept или Accept-Encoding с весом q
+type qValue struct {
+	value string
+	q     float64
+}
+
+// Функция разбора списка значений с весами (RFC 9110, раздел 12.4.2).
+// Параметры, кроме q, отбрасываются; значения приводятся к нижнему регистру.
+func parseQList(header string) []qValue {
+	var result []qValue
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		item := qValue{value: value, q: 1}
+		for _, param := range params[1:] {
+			name, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			item.q = q
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// Функция приведения имени формата к каноническому
+func canonicalMediaType(mediaType string) string {
+	if canonical, ok := mediaTypeAliases[mediaType]; ok {
+		return canonical
+	}
+	return mediaType
+}
+
+// Функция для выбора формата ответа по заголовку Accept из предложенных
+// offers в порядке предпочтения сервера. Без заголовка выбирается первый
+// формат; false — клиент не принимает ни один из них.
+func negotiateContentType(r *http.Request, offers ...string) (string, bool) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offers[0], true
+	}
+	ranges := parseQList(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		offerType, _, _ := strings.Cut(offer, "/")
+		// Вес определяет наиболее конкретный подходящий диапазон
+		q, specificity := 0.0, 0
+		for _, rng := range ranges {
+			value := canonicalMediaType(rng.value)
+			switch {
+			case value == offer && specificity < 3:
+				q, specificity = rng.q, 3
+			case value == offerType+"/*" && specificity < 2:
+				q, specificity = rng.q, 2
+			case value == "*/*" && specificity < 1:
+				q, specificity = rng.q, 1
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// Функция определения формата тела запроса по Content-Type. Без заголовка
+// тело считается JSON.
+func requestContentType(r *http.Request, supported ...string) (string, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return ContentTypeJSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", errUnsupportedMediaType, header)
+	}
+	mediaType = canonicalMediaType(mediaType)
+	for _, s := range supported {
+		if mediaType == s {
+			return mediaType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s, expected one of %s", errUnsupportedMediaType, mediaType, strings.Join(supported, ", "))
+}
+
+// Функция для разбора тела запроса в формате JSON, MessagePack или Protobuf.
+// JSON и MessagePack разбираются в v; для Protobuf тело целиком передаётся
+// в fromProto, который разбирает сообщение и заполняет v.
+func decodeBody(r *http.Request, v any, fromProto func(data []byte) error) error {
+	contentType, err := requestContentType(r, ContentTypeJSON, ContentTypeMsgPack, ContentTypeProtobuf)
+	if err != nil {
+		return err
+	}
+	switch contentType {
+	case ContentTypeMsgPack:
+		decoder := msgpack.NewDecoder(r.Body)
+		decoder.SetCustomStructTag("json")
+		err = decoder.Decode(v)
+	case ContentTypeProtobuf:
+		var data []byte
+		if data, err = io.ReadAll(r.Body); err == nil {
+			err = fromProto(data)
+		}
+	default:
+		err = json.NewDecoder(r.Body).Decode(v)
+	}
+	if err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+// Функция разбора сообщения Protobuf для decodeBody
+func unmarshalProto(data []byte, message proto.Message, convert func()) error {
+	if err := proto.Unmarshal(data, message); err != nil {
+		return err
+	}
+	convert()
+	return nil
+}
+
+// Ответ, который может быть отправлен в нескольких форматах
+type representation struct {
+	// Значение для JSON и MessagePack
+	value any
+	// Сообщение для Protobuf
+	message func() proto.Message
+	// Запись в CSV; nil — ответ не представим в CSV
+	csv func(w *csv.Writer) error
+	// Версия данных для ETag; 0 — ETag не передаётся
+	version int64
+	// Время изменения данных для Last-Modified; нулевое — не передаётся
+	modified time.Time
+}
+
+// Метод, возвращающий доступные форматы в порядке предпочтения
+func (rep representation) offers() []string {
+	offers := []string{ContentTypeJSON, ContentTypeMsgPack, ContentTypeProtobuf}
+	if rep.csv != nil {
+		offers = append(offers, ContentTypeCSV)
+	}
+	return offers
+}
+
+// Функция для отправки ответа в формате, выбранном по заголовку Accept.
+// Если клиент не принимает ни один из форматов, отправляется ошибка 406.
+// Для версионированных данных передаются ETag и Last-Modified, а при
+// выполнении условий If-None-Match или If-Modified-Since — ответ 304 без тела.
+func writeRepresentation(w http.ResponseWriter, r *http.Request, rep representation) {
+	offers := rep.offers()
+	w.Header().Add("Vary", "Accept")
+	contentType, ok := negotiateContentType(r, offers...)
+	if !ok {
+		problem := newProblem(http.StatusNotAcceptable, "not-acceptable", "Not acceptable",
+			"The response is available as "+strings.Join(offers, ", ")+".")
+		problem.Instance = r.URL.Path
+		writeProblem(w, r, problem)
+		return
+	}
+
+	var etag string
+	if rep.version > 0 {
+		etag = entityTag(rep.version, contentType)
+		w.Header().Set("ETag", etag)
+	}
+	if !rep.modified.IsZero() {
+		w.Header().Set("Last-Modified", rep.modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, rep.modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	switch contentType {
+	case ContentTypeMsgPack:
+		w.Header().Set("Content-Type", ContentTypeMsgPack)
+		w.WriteHeader(http.StatusOK)
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		encoder.Encode(rep.value)
+	case ContentTypeProtobuf:
+		data, err := proto.Marshal(rep.message())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeProtobuf)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	case ContentTypeCSV:
+		w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		writer := csv.NewWriter(w)
+		if rep.csv(writer) == nil {
+			writer.Flush()
+		}
+	default:
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rep.value)
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/services"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{ContentTypeJSON, ContentTypeMsgPack, ContentTypeProtobuf}
+	for accept, expected := range map[string]string{
+		"":                                ContentTypeJSON,
+		"*/*":                             ContentTypeJSON,
+		"application/msgpack":             ContentTypeMsgPack,
+		"application/x-msgpack":           ContentTypeMsgPack,
+		"application/protobuf, */*;q=0.1": ContentTypeProtobuf,
+		"application/*;q=0.5, application/json;q=0.1": ContentTypeMsgPack,
+		"text/html, application/json;q=0.9":           ContentTypeJSON,
+		"application/json;q=0, */*":                   ContentTypeMsgPack,
+		"text/html":                                   "",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		contentType, ok := negotiateContentType(r, offers...)
+		assert.Equal(t, expected, contentType, accept)
+		assert.Equal(t, expected != "", ok, accept)
+	}
+}
+
+func TestGetOrderBookHandler_Encodings(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+	orderBook := []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}, {Price: 50500, BaseQty: 0.2}}
+	mockService.On("GetOrderBook", mock.Anything, "binance", "BTC/USDT").Return(orderBook, nil)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orderbook/get?exchange_name=binance&pair=BTC/USDT", nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		GetOrderBookHandler(service).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("application/msgpack")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ContentTypeMsgPack, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+	var decoded []map[string]float64
+	require.NoError(t, msgpack.Unmarshal(rr.Body.Bytes(), &decoded))
+	assert.Equal(t, []map[string]float64{{"price": 50000, "base_qty": 0.1}, {"price": 50500, "base_qty": 0.2}}, decoded)
+
+	rr = get("application/x-protobuf")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ContentTypeProtobuf, rr.Header().Get("Content-Type"))
+	var message statsv1.GetOrderBookResponse
+	require.NoError(t, proto.Unmarshal(rr.Body.Bytes(), &message))
+	require.Len(t, message.GetOrderBook(), 2)
+	assert.Equal(t, 50500.0, message.GetOrderBook()[1].GetPrice())
+
+	// CSV доступен только для истории ордеров
+	rr = get("text/csv")
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "/problems/not-acceptable", problem.Type)
+}
+
+func TestGetOrderHistoryHandler_CSV(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+	placed := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	history := []*models.HistoryOrder{{
+		ClientName: "test_client", ExchangeName: "binance", Label: "a,b", Pair: "BTC/USDT",
+		Side: "buy", Type: "limit", BaseQty: 0.5, Price: 50000, TimePlaced: placed,
+	}}
+	mockService.On("GetOrderHistory", mock.Anything, &models.Client{ClientName: "test_client"}).Return(history, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/orderhistory/get", bytes.NewBufferString(`{"client_name":"test_client"}`))
+	req.Header.Set("Accept", "text/csv")
+	rr := httptest.NewRecorder()
+	GetOrderHistoryHandler(service).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, historyCSVHeader, records[0])
+	assert.Equal(t, []string{
+		"test_client", "binance", "a,b", "BTC/USDT", "buy", "limit", "0.5", "50000",
+		"", "0", "0", "0", "2024-03-01T12:30:00Z",
+	}, records[1])
+}
+
+func TestSaveOrderBookHandler_Encodings(t *testing.T) {
+	orderBook := []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}}
+	msgpackBody, err := msgpack.Marshal(map[string]any{
+		"exchange_name": "binance",
+		"pair":          "BTC/USDT",
+		"order_book":    []map[string]float64{{"price": 50000, "base_qty": 0.1}},
+	})
+	require.NoError(t, err)
+	protoBody, err := proto.Marshal(&statsv1.SaveOrderBookRequest{
+		ExchangeName: "binance",
+		Pair:         "BTC/USDT",
+		OrderBook:    []*statsv1.DepthOrder{{Price: 50000, BaseQty: 0.1}},
+	})
+	require.NoError(t, err)
+
+	for contentType, body := range map[string][]byte{
+		"application/msgpack":    msgpackBody,
+		"application/x-protobuf": protoBody,
+	} {
+		mockService := new(MockService)
+		service := &services.Service{Repo: mockService}
+		mockService.On("SaveOrderBook", mock.Anything, "binance", "BTC/USDT", orderBook).Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/orderbook/save", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		SaveOrderBookHandler(service).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, contentType)
+		mockService.AssertExpectations(t)
+	}
+}
+
+func TestSaveOrderHandler_UnsupportedMediaType(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	req := httptest.NewRequest(http.MethodPost, "/order/save", bytes.NewBufferString("client_name=test"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	SaveOrderHandler(service).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "/problems/unsupported-media-type", problem.Type)
+	mockService.AssertNotCalled(t, "SaveOrder", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/requestid"
+	"StatisticsCollectionService/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Нестандартный код ответа для запросов, отменённых клиентом (по аналогии с nginx)
+const StatusClientClosedRequest = 499
+
+// Тип содержимого ответов с ошибками (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Префикс URI типов ошибок
+const problemTypePrefix = "/problems/"
+
+// Ошибка разбора тела или параметров запроса
+var errBadRequest = errors.New("bad request")
+
+// Описание ошибки в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string                `json:"type" example:"/problems/validation-failed"`      // URI типа ошибки
+	Title     string                `json:"title" example:"Validation failed"`               // Краткое описание типа ошибки
+	Status    int                   `json:"status" example:"422"`                            // HTTP-код ответа
+	Detail    string                `json:"detail,omitempty"`                                // Описание конкретного случая
+	Instance  string                `json:"instance,omitempty" example:"/api/v1/order/save"` // Путь запроса
+	RequestID string                `json:"request_id,omitempty"`                            // Идентификатор запроса (X-Request-ID)
+	Errors    []services.FieldError `json:"errors,omitempty"`                                // Ошибки проверки полей
+}
+
+// Функция для оборачивания ошибки разбора запроса
+func badRequest(err error) error {
+	return fmt.Errorf("%w: %w", errBadRequest, err)
+}
+
+// Функция для отправки ошибки в формате application/problem+json с кодом,
+// соответствующим её причине. Текст внутренних ошибок клиенту не передаётся.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFor(r.Context(), err)
+	problem.Instance = r.URL.Path
+	writeProblem(w, r, problem)
+}
+
+// Функция для отправки описания ошибки
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.RequestID = requestid.FromContext(r.Context())
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// Функция для создания описания ошибки по коду ответа
+func newProblem(status int, code, title, detail string) Problem {
+	return Problem{Type: problemTypePrefix + code, Title: title, Status: status, Detail: detail}
+}
+
+// Функция для построения описания ошибки по её причине и состоянию контекста запроса
+func problemFor(ctx context.Context, err error) Problem {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	var validationErr *services.ValidationError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return newProblem(http.StatusGatewayTimeout, "timeout", "Request timed out", "The request was not completed within the configured deadline.")
+	case errors.Is(err, context.Canceled):
+		return newProblem(StatusClientClosedRequest, "client-closed-request", "Client closed request", "The request was cancelled by the client.")
+	case errors.Is(err, errUnsupportedMediaType):
+		return newProblem(http.StatusUnsupportedMediaType, "unsupported-media-type", "Unsupported media type", err.Error())
+	case errors.Is(err, errBadRequest):
+		return newProblem(http.StatusBadRequest, "bad-request", "Bad request", err.Error())
+	case errors.As(err, &validationErr):
+		problem := newProblem(http.StatusUnprocessableEntity, "validation-failed", "Validation failed", "One or more fields are invalid.")
+		problem.Errors = validationErr.Fields
+		return problem
+	case errors.Is(err, repository.ErrInvalid):
+		return newProblem(http.StatusUnprocessableEntity, "invalid-data", "Invalid data", "The data was rejected by the storage.")
+	case errors.Is(err, repository.ErrNotFound):
+		return newProblem(http.StatusNotFound, "not-found", "Not found", "The requested resource does not exist.")
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return newProblem(http.StatusPreconditionFailed, "precondition-failed", "Precondition failed", "The resource was modified or does not exist; fetch the current version and retry.")
+	case errors.Is(err, repository.ErrConflict):
+		return newProblem(http.StatusConflict, "conflict", "Conflict", "The request conflicts with the current state of the resource.")
+	case errors.Is(err, repository.ErrUnavailable):
+		return newProblem(http.StatusServiceUnavailable, "unavailable", "Service unavailable", "The storage is temporarily unavailable, retry later.")
+	default:
+		return newProblem(http.StatusInternalServerError, "internal", "Internal server error", "")
+	}
+}
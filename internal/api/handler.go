@@ -1,42 +1,87 @@
 package api
 
 import (
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
 	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/protoconv"
 	"StatisticsCollectionService/internal/services"
-	"encoding/json"
+	"encoding/csv"
 	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // @Summary Получить книгу ордеров
-// @Description Получить книгу ордеров для указанной биржи и пары валют
+// @Description Получить книгу ордеров для указанной биржи и пары валют.
+// @Description Формат ответа выбирается по заголовку Accept: JSON, MessagePack или Protobuf (GetOrderBookResponse).
+// @Produce json
+// @Produce application/msgpack
+// @Produce application/x-protobuf
+// @Produce application/problem+json
 // @Param exchange_name query string true "Имя биржи"
 // @Param pair query string true "Валютная пара"
+// @Param If-None-Match header string false "ETag имеющейся у клиента версии"
+// @Param If-Modified-Since header string false "Время последнего полученного изменения"
 // @Success 200 {array} models.DepthOrder
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
-// @Router /orderbook/get [get]
+// @Header 200 {string} ETag "Версия книги ордеров"
+// @Header 200 {string} Last-Modified "Время сохранения книги ордеров"
+// @Success 304 "Книга ордеров не изменилась"
+// @Failure 404 {object} api.Problem "Книга ордеров не найдена"
+// @Failure 406 {object} api.Problem "Неподдерживаемый формат ответа"
+// @Failure 401 {object} api.Problem "Требуется ключ API"
+// @Failure 422 {object} api.Problem "Ошибка проверки данных"
+// @Failure 429 {object} api.Problem "Превышено ограничение частоты запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} api.Problem "Хранилище недоступно"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security ApiKeyAuth
+// @Router /api/v1/orderbook/get [get]
 func GetOrderBookHandler(service *services.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		exchangeName := r.URL.Query().Get("exchange_name")
 		pair := r.URL.Query().Get("pair")
 
-		orderBook, err := service.GetOrderBook(exchangeName, pair)
+		snapshot, err := service.GetOrderBookSnapshot(r.Context(), exchangeName, pair)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(orderBook)
+		writeRepresentation(w, r, representation{
+			value: snapshot.OrderBook,
+			message: func() proto.Message {
+				return &statsv1.GetOrderBookResponse{OrderBook: protoconv.DepthOrdersToProto(snapshot.OrderBook)}
+			},
+			version:  snapshot.Version,
+			modified: snapshot.UpdatedAt,
+		})
 	}
 }
 
 // @Summary Сохранить книгу ордеров
-// @Description Сохранить книгу ордеров для указанной биржи и пары валют
+// @Description Сохранить книгу ордеров для указанной биржи и пары валют.
+// @Description Тело принимается в JSON, MessagePack или Protobuf (SaveOrderBookRequest) по заголовку Content-Type.
+// @Accept json
+// @Accept application/msgpack
+// @Accept application/x-protobuf
+// @Produce application/problem+json
 // @Param order body models.OrderBook true "Книга ордеров"
+// @Param If-Match header string false "ETag версии, которую необходимо заменить, или *"
 // @Success 200 {string} string "OK"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
-// @Router /orderbook/save [post]
+// @Header 200 {string} ETag "Версия сохранённой книги ордеров"
+// @Failure 400 {object} api.Problem "Некорректный запрос"
+// @Failure 415 {object} api.Problem "Неподдерживаемый формат тела запроса"
+// @Failure 409 {object} api.Problem "Конфликт данных"
+// @Failure 412 {object} api.Problem "Версия книги ордеров изменилась"
+// @Failure 401 {object} api.Problem "Требуется ключ API"
+// @Failure 422 {object} api.Problem "Ошибка проверки данных"
+// @Failure 429 {object} api.Problem "Превышено ограничение частоты запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} api.Problem "Хранилище недоступно"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security ApiKeyAuth
+// @Router /api/v1/orderbook/save [post]
 func SaveOrderBookHandler(service *services.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var request struct {
@@ -45,58 +90,115 @@ func SaveOrderBookHandler(service *services.Service) http.HandlerFunc {
 			OrderBook    []*models.DepthOrder `json:"order_book"`
 		}
 
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := decodeBody(r, &request, func(data []byte) error {
+			var message statsv1.SaveOrderBookRequest
+			return unmarshalProto(data, &message, func() {
+				request.ExchangeName = message.GetExchangeName()
+				request.Pair = message.GetPair()
+				request.OrderBook = protoconv.DepthOrdersFromProto(message.GetOrderBook())
+			})
+		})
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		err := service.SaveOrderBook(request.ExchangeName, request.Pair, request.OrderBook)
+		expectedVersion, err := ifMatchVersion(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
+		snapshot, err := service.SaveOrderBookSnapshot(r.Context(), request.ExchangeName, request.Pair, request.OrderBook, expectedVersion)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", entityTag(snapshot.Version, ContentTypeJSON))
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
 // @Summary Получить историю ордеров
-// @Description Получить историю ордеров для указанного клиента
+// @Description Получить историю ордеров для указанного клиента.
+// @Description Тело принимается в JSON, MessagePack или Protobuf (Client); ответ — в JSON, MessagePack,
+// @Description Protobuf (GetOrderHistoryResponse) или CSV по заголовку Accept.
+// @Accept json
+// @Accept application/msgpack
+// @Accept application/x-protobuf
+// @Produce json
+// @Produce application/msgpack
+// @Produce application/x-protobuf
+// @Produce text/csv
+// @Produce application/problem+json
 // @Param client body models.Client true "Клиент"
 // @Success 200 {array} models.HistoryOrder
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
-// @Router /orderhistory/get [get]
+// @Failure 400 {object} api.Problem "Некорректный запрос"
+// @Failure 415 {object} api.Problem "Неподдерживаемый формат тела запроса"
+// @Failure 406 {object} api.Problem "Неподдерживаемый формат ответа"
+// @Failure 401 {object} api.Problem "Требуется ключ API"
+// @Failure 422 {object} api.Problem "Ошибка проверки данных"
+// @Failure 429 {object} api.Problem "Превышено ограничение частоты запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} api.Problem "Хранилище недоступно"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security ApiKeyAuth
+// @Router /api/v1/orderhistory/get [get]
 func GetOrderHistoryHandler(service *services.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var client models.Client
-		if err := json.NewDecoder(r.Body).Decode(&client); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := decodeBody(r, &client, func(data []byte) error {
+			var message statsv1.Client
+			return unmarshalProto(data, &message, func() { client = *protoconv.ClientFromProto(&message) })
+		})
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		history, err := service.GetOrderHistory(&client)
+		history, err := service.GetOrderHistory(r.Context(), &client)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(history)
+		writeRepresentation(w, r, representation{
+			value: history,
+			message: func() proto.Message {
+				return &statsv1.GetOrderHistoryResponse{Orders: protoconv.HistoryToProto(history)}
+			},
+			csv: func(w *csv.Writer) error { return writeHistoryCSV(w, history) },
+		})
 	}
 }
 
 // @Summary Сохранить ордер
-// @Description Сохранить новый ордер для указанного клиента
+// @Description Сохранить новый ордер для указанного клиента.
+// @Description Тело принимается в JSON, MessagePack или Protobuf (HistoryOrder) по заголовку Content-Type.
+// @Accept json
+// @Accept application/msgpack
+// @Accept application/x-protobuf
+// @Produce application/problem+json
 // @Param order body models.HistoryOrder true "Ордер"
 // @Success 200 {string} string "OK"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
-// @Router /order/save [post]
+// @Failure 400 {object} api.Problem "Некорректный запрос"
+// @Failure 415 {object} api.Problem "Неподдерживаемый формат тела запроса"
+// @Failure 409 {object} api.Problem "Конфликт данных"
+// @Failure 401 {object} api.Problem "Требуется ключ API"
+// @Failure 422 {object} api.Problem "Ошибка проверки данных"
+// @Failure 429 {object} api.Problem "Превышено ограничение частоты запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} api.Problem "Хранилище недоступно"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security ApiKeyAuth
+// @Router /api/v1/order/save [post]
 func SaveOrderHandler(service *services.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var order models.HistoryOrder
-		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := decodeBody(r, &order, func(data []byte) error {
+			var message statsv1.HistoryOrder
+			return unmarshalProto(data, &message, func() { order = *protoconv.HistoryOrderFromProto(&message) })
+		})
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
@@ -106,11 +208,46 @@ func SaveOrderHandler(service *services.Service) http.HandlerFunc {
 			Label:        order.Label,
 			Pair:         order.Pair,
 		}
-		err := service.SaveOrder(client, &order)
+		err = service.SaveOrder(r.Context(), client, &order)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// Заголовок CSV-представления истории ордеров: имена полей JSON
+var historyCSVHeader = []string{
+	"client_name", "exchange_name", "label", "pair", "side", "type", "base_qty", "price",
+	"algorithm_name_placed", "lowest_sell_prc", "highest_buy_prc", "commission_quote_qty", "time_placed",
+}
+
+// Функция записи истории ордеров в CSV
+func writeHistoryCSV(w *csv.Writer, history []*models.HistoryOrder) error {
+	if err := w.Write(historyCSVHeader); err != nil {
+		return err
+	}
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	for _, order := range history {
+		err := w.Write([]string{
+			order.ClientName,
+			order.ExchangeName,
+			order.Label,
+			order.Pair,
+			order.Side,
+			order.Type,
+			formatFloat(order.BaseQty),
+			formatFloat(order.Price),
+			order.AlgorithmNamePlaced,
+			formatFloat(order.LowestSellPrice),
+			formatFloat(order.HighestBuyPrice),
+			formatFloat(order.CommissionQuoteQty),
+			order.TimePlaced.UTC().Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,40 +2,72 @@ package api
 
 import (
 	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/requestid"
 	"StatisticsCollectionService/internal/services"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) GetOrderBook(exchangeName, pair string) ([]*models.DepthOrder, error) {
-	args := m.Called(exchangeName, pair)
+func (m *MockService) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	args := m.Called(ctx, exchangeName, pair)
 	return args.Get(0).([]*models.DepthOrder), args.Error(1)
 }
 
-func (m *MockService) SaveOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error {
-	args := m.Called(exchangeName, pair, orderBook)
+func (m *MockService) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	args := m.Called(ctx, exchangeName, pair, orderBook)
 	return args.Error(0)
 }
 
-func (m *MockService) GetOrderHistory(client *models.Client) ([]*models.HistoryOrder, error) {
-	args := m.Called(client)
+// Снимок строится из GetOrderBook, чтобы тесты задавали ожидания одного метода
+func (m *MockService) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	orderBook, err := m.GetOrderBook(ctx, exchangeName, pair)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrderBookSnapshot{OrderBook: orderBook, Version: 1}, nil
+}
+
+// Безусловное сохранение сводится к SaveOrderBook
+func (m *MockService) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	if expectedVersion > 0 {
+		args := m.Called(ctx, exchangeName, pair, orderBook, expectedVersion)
+		snapshot, _ := args.Get(0).(*models.OrderBookSnapshot)
+		return snapshot, args.Error(1)
+	}
+	if err := m.SaveOrderBook(ctx, exchangeName, pair, orderBook); err != nil {
+		return nil, err
+	}
+	return &models.OrderBookSnapshot{OrderBook: orderBook, Version: 1}, nil
+}
+
+func (m *MockService) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	args := m.Called(ctx, client)
 	return args.Get(0).([]*models.HistoryOrder), args.Error(1)
 }
 
-func (m *MockService) SaveOrder(client *models.Client, order *models.HistoryOrder) error {
-	args := m.Called(client, order)
+func (m *MockService) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	args := m.Called(ctx, client, order)
 	return args.Error(0)
 }
 
+func (m *MockService) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return fn(m)
+}
+
 func TestGetOrderBookHandler(t *testing.T) {
 	mockService := new(MockService)
 	service := &services.Service{Repo: mockService}
@@ -47,7 +79,7 @@ func TestGetOrderBookHandler(t *testing.T) {
 		{Price: 50500, BaseQty: 0.2},
 	}
 
-	mockService.On("GetOrderBook", exchangeName, pair).Return(expectedOrderBook, nil)
+	mockService.On("GetOrderBook", mock.Anything, exchangeName, pair).Return(expectedOrderBook, nil)
 
 	req, err := http.NewRequest("GET", "/orderbook/get?exchange_name="+exchangeName+"&pair="+pair, nil)
 	assert.NoError(t, err)
@@ -76,7 +108,7 @@ func TestSaveOrderBookHandler(t *testing.T) {
 		{Price: 49000, BaseQty: 0.2},
 	}
 
-	mockService.On("SaveOrderBook", exchangeName, pair, orderBook).Return(nil)
+	mockService.On("SaveOrderBook", mock.Anything, exchangeName, pair, orderBook).Return(nil)
 
 	requestBody, err := json.Marshal(map[string]interface{}{
 		"exchange_name": exchangeName,
@@ -107,7 +139,7 @@ func TestGetOrderHistoryHandler(t *testing.T) {
 		{ClientName: "test_client", ExchangeName: "binance", Pair: "BTC/USDT"},
 	}
 
-	mockService.On("GetOrderHistory", client).Return(expectedHistory, nil)
+	mockService.On("GetOrderHistory", mock.Anything, client).Return(expectedHistory, nil)
 
 	requestBody, err := json.Marshal(client)
 	assert.NoError(t, err)
@@ -144,7 +176,7 @@ func TestSaveOrderHandler(t *testing.T) {
 		Pair:         order.Pair,
 	}
 
-	mockService.On("SaveOrder", client, order).Return(nil)
+	mockService.On("SaveOrder", mock.Anything, client, order).Return(nil)
 
 	requestBody, err := json.Marshal(order)
 	assert.NoError(t, err)
@@ -161,3 +193,162 @@ func TestSaveOrderHandler(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestGetOrderBookHandler_Timeout(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	mockService.On("GetOrderBook", mock.Anything, "binance", "BTC/USDT").
+		Return([]*models.DepthOrder(nil), context.DeadlineExceeded)
+
+	req, err := http.NewRequest("GET", "/orderbook/get?exchange_name=binance&pair=BTC/USDT", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := WithTimeout(time.Second, GetOrderBookHandler(service))
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetOrderHistoryHandler_ClientClosedRequest(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &models.Client{ClientName: "test_client"}
+	mockService.On("GetOrderHistory", mock.Anything, client).
+		Run(func(mock.Arguments) { cancel() }).
+		Return([]*models.HistoryOrder(nil), errors.New("pq: canceling statement due to user request"))
+
+	requestBody, err := json.Marshal(client)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/orderhistory/get", bytes.NewBuffer(requestBody))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := GetOrderHistoryHandler(service)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, StatusClientClosedRequest, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWithTimeout_SetsDeadline(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := WithTimeout(time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	})
+
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestGetOrderBookHandler_NotFound(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	driverErr := fmt.Errorf("%w: %w", repository.ErrNotFound, errors.New("sql: no rows in result set"))
+	mockService.On("GetOrderBook", mock.Anything, "binance", "BTC/USDT").
+		Return([]*models.DepthOrder(nil), driverErr)
+
+	req, err := http.NewRequest("GET", "/orderbook/get?exchange_name=binance&pair=BTC/USDT", nil)
+	assert.NoError(t, err)
+	req.Header.Set(requestid.Header, "req-1")
+
+	rr := httptest.NewRecorder()
+	requestid.Middleware(GetOrderBookHandler(service)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	assert.NotContains(t, rr.Body.String(), "sql:")
+
+	var body Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, Problem{
+		Type:      "/problems/not-found",
+		Title:     "Not found",
+		Status:    http.StatusNotFound,
+		Detail:    "The requested resource does not exist.",
+		Instance:  "/orderbook/get",
+		RequestID: "req-1",
+	}, body)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetOrderBookHandler_ValidationError(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	req, err := http.NewRequest("GET", "/orderbook/get?exchange_name=binance", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	GetOrderBookHandler(service).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var body Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "/problems/validation-failed", body.Type)
+	assert.Equal(t, []services.FieldError{{Field: "pair", Message: "is required"}}, body.Errors)
+	mockService.AssertNotCalled(t, "GetOrderBook", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSaveOrderHandler_MalformedBody(t *testing.T) {
+	mockService := new(MockService)
+	service := &services.Service{Repo: mockService}
+
+	req, err := http.NewRequest("POST", "/order/save", bytes.NewBufferString("{not json"))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	SaveOrderHandler(service).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "/problems/bad-request", body.Type)
+	assert.Equal(t, http.StatusBadRequest, body.Status)
+}
+
+func TestSaveOrderBookHandler_StorageErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: %w", repository.ErrConflict, errors.New("pq: duplicate key value")), http.StatusConflict},
+		{fmt.Errorf("%w: %w", repository.ErrUnavailable, errors.New("dial tcp: connection refused")), http.StatusServiceUnavailable},
+		{errors.New("pq: relation \"order_books\" does not exist"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		mockService := new(MockService)
+		service := &services.Service{Repo: mockService}
+		orderBook := []*models.DepthOrder{{Price: 1, BaseQty: 1}}
+		mockService.On("SaveOrderBook", mock.Anything, "binance", "BTC/USDT", orderBook).Return(tt.err)
+
+		requestBody, err := json.Marshal(map[string]interface{}{
+			"exchange_name": "binance",
+			"pair":          "BTC/USDT",
+			"order_book":    orderBook,
+		})
+		assert.NoError(t, err)
+		req, err := http.NewRequest("POST", "/orderbook/save", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		SaveOrderBookHandler(service).ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.status, rr.Code)
+		assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		assert.NotContains(t, rr.Body.String(), "pq:")
+		assert.NotContains(t, rr.Body.String(), "dial tcp")
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/ratelimit"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Функция получения адреса клиента из адреса соединения
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Функция выбора ключа ограничения: владелец ключа API или адрес клиента.
+// Имена, которые клиент сообщает о себе сам, не учитываются: иначе клиент
+// без ключа обходил бы ограничение, меняя имя в каждом запросе.
+func rateLimitKey(r *http.Request) string {
+	if name := KeyNameFromContext(r.Context()); name != "" {
+		return "key:" + name
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// Middleware ограничения частоты запросов. Ограничитель выбирается по классу
+// RateLimit маршрута; маршруты без класса или без ограничителя не
+// ограничиваются. Ответы получают заголовки RateLimit-Limit,
+// RateLimit-Remaining и RateLimit-Reset, отклонённые запросы — 429 с
+// Retry-After. Функция limited вызывается для каждого отклонённого запроса.
+// Должно работать после Authenticate, чтобы учитывать владельца ключа API.
+func RateLimit(limiters map[string]*ratelimit.Limiter, limited func(r *http.Request, class string)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := RouteFromContext(r.Context())
+			if route == nil || limiters[route.RateLimit] == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			d := limiters[route.RateLimit].Allow(rateLimitKey(r))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(d.Reset)))
+			if d.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if limited != nil {
+				limited(r, route.RateLimit)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds(d.RetryAfter), 1)))
+			problem := newProblem(http.StatusTooManyRequests, "rate-limited", "Too many requests",
+				"The "+route.RateLimit+" rate limit is exceeded, retry later.")
+			problem.Instance = r.URL.Path
+			writeProblem(w, r, problem)
+		})
+	}
+}
+
+// Функция округления длительности вверх до целых секунд для заголовков
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"StatisticsCollectionService/internal/ratelimit"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	router := newTestRouter()
+	var limited []string
+	router.Use(
+		Authenticate(APIKeys{"abc": "collector-1"}),
+		RateLimit(map[string]*ratelimit.Limiter{
+			RateLimitRead:  ratelimit.New(ratelimit.Limit{Rate: 0.001, Burst: 2}),
+			RateLimitWrite: ratelimit.New(ratelimit.Limit{Rate: 0.001, Burst: 1}),
+		}, func(r *http.Request, class string) { limited = append(limited, RoutePattern(r)+" "+class) }),
+	)
+
+	request := func(method, path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/v1/orderbook/get", APIKeyHeader, "abc")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	// Прежний путь использует то же ограничение
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/orderbook/get", APIKeyHeader, "abc").Code)
+	w = request(http.MethodGet, "/api/v1/orderbook/get", APIKeyHeader, "abc")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1000", w.Header().Get("Retry-After"))
+	var problem Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+	assert.Equal(t, "/problems/rate-limited", problem.Type)
+
+	// Запись ограничивается отдельно от чтения
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/orderbook/save", APIKeyHeader, "abc").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/api/v1/orderbook/save", APIKeyHeader, "abc").Code)
+
+	// Клиенты без ключа различаются по адресу; имя из заголовка ограничение не обходит
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/orderbook/save", "X-Client-Name", "John Doe").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/api/v1/orderbook/save", "X-Client-Name", "Jane Doe").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/api/v1/orderbook/save").Code)
+
+	// Маршруты без класса не ограничиваются
+	for i := 0; i < 3; i++ {
+		w = request(http.MethodGet, "/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+
+	assert.Equal(t, []string{
+		"/api/v1/orderbook/get read",
+		"/api/v1/orderbook/save write",
+		"/api/v1/orderbook/save write",
+		"/api/v1/orderbook/save write",
+	}, limited)
+}
@@ -0,0 +1,45 @@
+package api
+
+import "net/http"
+
+// Обёртка над http.ResponseWriter, запоминающая код ответа и размер тела.
+// Используется middleware, которым нужен результат обработки запроса.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+}
+
+// Конструктор для создания обёртки над http.ResponseWriter
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += n
+	return n, err
+}
+
+// Метод для доступа к исходному http.ResponseWriter через http.ResponseController
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Метод для получения кода ответа; обработчик, ничего не записавший, отвечает 200
+func (r *ResponseRecorder) StatusCode() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
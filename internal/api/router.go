@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Префикс путей текущей версии API
+const V1Prefix = "/api/v1"
+
+// Классы ограничения частоты запросов
+const (
+	RateLimitNone  = ""
+	RateLimitRead  = "read"
+	RateLimitWrite = "write"
+)
+
+// Middleware — обёртка над обработчиком запросов
+type Middleware func(http.Handler) http.Handler
+
+// Маршрут и его метаданные. Путь, оканчивающийся на "/", совпадает со всеми
+// путями с этим префиксом. Маршрут с методом GET обслуживает и HEAD.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.Handler
+	// Требуется ли ключ API
+	Auth bool
+	// Класс ограничения частоты запросов
+	RateLimit string
+	// Путь маршрута, заменяющего устаревший; клиенту возвращаются заголовки
+	// Deprecation и Link
+	Successor string
+}
+
+// Результат сопоставления запроса с маршрутами
+type match struct {
+	route   *Route
+	pattern string
+	allowed []string
+}
+
+type routeKey struct{}
+
+// Функция для получения маршрута запроса; nil — маршрут не найден
+// или не поддерживает метод запроса
+func RouteFromContext(ctx context.Context) *Route {
+	if m, ok := ctx.Value(routeKey{}).(*match); ok {
+		return m.route
+	}
+	return nil
+}
+
+// Функция для получения шаблона пути запроса. Возвращает путь маршрута и для
+// неподдерживаемого метода; пустая строка — путь не найден.
+func RoutePattern(r *http.Request) string {
+	if m, ok := r.Context().Value(routeKey{}).(*match); ok {
+		return m.pattern
+	}
+	return ""
+}
+
+// Маршрутизатор HTTP-запросов. Сопоставляет запрос с маршрутом до выполнения
+// цепочки middleware, поэтому middleware видят метаданные маршрута. На
+// неподдерживаемый метод отвечает 405 с заголовком Allow, на неизвестный
+// путь — 404.
+type Router struct {
+	paths      map[string]map[string]*Route
+	prefixes   []string
+	middleware []Middleware
+	chain      http.Handler
+}
+
+// Конструктор для создания маршрутизатора
+func NewRouter() *Router {
+	rt := &Router{paths: make(map[string]map[string]*Route)}
+	rt.chain = http.HandlerFunc(rt.dispatch)
+	return rt
+}
+
+// Метод для добавления middleware. Middleware выполняются в порядке
+// добавления для всех запросов, в том числе не совпавших с маршрутами.
+func (rt *Router) Use(middleware ...Middleware) {
+	rt.middleware = append(rt.middleware, middleware...)
+	rt.chain = http.HandlerFunc(rt.dispatch)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		rt.chain = rt.middleware[i](rt.chain)
+	}
+}
+
+// Метод для добавления маршрута. Повторная регистрация метода и пути
+// является ошибкой программы.
+func (rt *Router) Handle(route Route) {
+	methods, ok := rt.paths[route.Path]
+	if !ok {
+		methods = make(map[string]*Route)
+		rt.paths[route.Path] = methods
+		if strings.HasSuffix(route.Path, "/") {
+			rt.prefixes = append(rt.prefixes, route.Path)
+			// Более длинный префикс проверяется раньше
+			sort.Slice(rt.prefixes, func(i, j int) bool { return len(rt.prefixes[i]) > len(rt.prefixes[j]) })
+		}
+	}
+	if _, ok := methods[route.Method]; ok {
+		panic("api: multiple registrations for " + route.Method + " " + route.Path)
+	}
+	methods[route.Method] = &route
+}
+
+// Метод для добавления маршрута в текущей версии API и устаревшего
+// маршрута по прежнему пути без префикса
+func (rt *Router) HandleV1(route Route) {
+	legacy := route
+	route.Path = V1Prefix + route.Path
+	legacy.Successor = route.Path
+	rt.Handle(route)
+	rt.Handle(legacy)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m := rt.match(r.Method, r.URL.Path)
+	rt.chain.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, m)))
+}
+
+// Метод сопоставления метода и пути запроса с маршрутами
+func (rt *Router) match(method, path string) *match {
+	pattern := path
+	methods, ok := rt.paths[path]
+	if !ok {
+		for _, prefix := range rt.prefixes {
+			if strings.HasPrefix(path, prefix) {
+				pattern, methods = prefix, rt.paths[prefix]
+				break
+			}
+		}
+	}
+	if methods == nil {
+		return &match{}
+	}
+
+	route := methods[method]
+	if route == nil && method == http.MethodHead {
+		route = methods[http.MethodGet]
+	}
+	m := &match{route: route, pattern: pattern}
+	if route == nil {
+		for allowed := range methods {
+			m.allowed = append(m.allowed, allowed)
+			if allowed == http.MethodGet {
+				m.allowed = append(m.allowed, http.MethodHead)
+			}
+		}
+		sort.Strings(m.allowed)
+	}
+	return m
+}
+
+// Конечный обработчик цепочки: вызывает обработчик маршрута или отвечает ошибкой
+func (rt *Router) dispatch(w http.ResponseWriter, r *http.Request) {
+	m, _ := r.Context().Value(routeKey{}).(*match)
+	switch {
+	case m != nil && m.route != nil:
+		if m.route.Successor != "" {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+m.route.Successor+`>; rel="successor-version"`)
+		}
+		m.route.Handler.ServeHTTP(w, r)
+	case m != nil && len(m.allowed) > 0:
+		w.Header().Set("Allow", strings.Join(m.allowed, ", "))
+		problem := newProblem(http.StatusMethodNotAllowed, "method-not-allowed", "Method not allowed",
+			"The method "+r.Method+" is not supported by this resource.")
+		problem.Instance = r.URL.Path
+		writeProblem(w, r, problem)
+	default:
+		problem := newProblem(http.StatusNotFound, "not-found", "Not found", "The requested resource does not exist.")
+		problem.Instance = r.URL.Path
+		writeProblem(w, r, problem)
+	}
+}
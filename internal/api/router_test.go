@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Method + " " + RoutePattern(r)))
+}
+
+func newTestRouter() *Router {
+	router := NewRouter()
+	router.Handle(Route{Method: http.MethodGet, Path: "/healthz", Handler: http.HandlerFunc(ok)})
+	router.HandleV1(Route{Method: http.MethodGet, Path: "/orderbook/get", Handler: http.HandlerFunc(ok), Auth: true, RateLimit: RateLimitRead})
+	router.HandleV1(Route{Method: http.MethodPost, Path: "/orderbook/save", Handler: http.HandlerFunc(ok), RateLimit: RateLimitWrite})
+	router.Handle(Route{Method: http.MethodGet, Path: "/swagger/", Handler: http.HandlerFunc(ok)})
+	return router
+}
+
+func serve(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRouter(t *testing.T) {
+	router := newTestRouter()
+
+	w := serve(router, http.MethodGet, "/api/v1/orderbook/get")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "GET /api/v1/orderbook/get", w.Body.String())
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	w = serve(router, http.MethodHead, "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, http.MethodGet, "/swagger/index.html")
+	assert.Equal(t, "GET /swagger/", w.Body.String())
+}
+
+func TestRouter_LegacyAlias(t *testing.T) {
+	w := serve(newTestRouter(), http.MethodPost, "/orderbook/save")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "POST /orderbook/save", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/orderbook/save>; rel="successor-version"`, w.Header().Get("Link"))
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	router := newTestRouter()
+	for path, allow := range map[string]string{
+		"/api/v1/orderbook/save": "POST",
+		"/orderbook/get":         "GET, HEAD",
+	} {
+		w := serve(router, http.MethodPut, path)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code, path)
+		assert.Equal(t, allow, w.Header().Get("Allow"), path)
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+		assert.Equal(t, "/problems/method-not-allowed", problem.Type)
+		assert.Equal(t, path, problem.Instance)
+	}
+}
+
+func TestRouter_NotFound(t *testing.T) {
+	w := serve(newTestRouter(), http.MethodGet, "/api/v2/orderbook/get")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Allow"))
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	router := newTestRouter()
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				route := RouteFromContext(r.Context())
+				if route != nil {
+					calls = append(calls, name+":"+route.RateLimit)
+				} else {
+					calls = append(calls, name+":"+RoutePattern(r))
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	router.Use(record("first"), record("second"))
+
+	serve(router, http.MethodGet, "/api/v1/orderbook/get")
+	serve(router, http.MethodGet, "/api/v1/orderbook/save")
+	serve(router, http.MethodGet, "/unknown")
+	assert.Equal(t, []string{
+		"first:read", "second:read",
+		"first:/api/v1/orderbook/save", "second:/api/v1/orderbook/save",
+		"first:", "second:",
+	}, calls)
+}
+
+func TestRouter_DuplicateRoute(t *testing.T) {
+	router := newTestRouter()
+	assert.Panics(t, func() {
+		router.Handle(Route{Method: http.MethodGet, Path: "/healthz", Handler: http.HandlerFunc(ok)})
+	})
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Функция-обёртка, ограничивающая время обработки запроса
+func WithTimeout(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if timeout <= 0 {
+			next(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
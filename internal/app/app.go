@@ -0,0 +1,366 @@
+// Пакет app собирает сервис из конфигурации: открывает хранилище, создаёт
+// репозиторий, сервис и HTTP-маршруты и управляет их жизненным циклом.
+package app
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/grpcapi"
+	"StatisticsCollectionService/internal/health"
+	"StatisticsCollectionService/internal/logging"
+	"StatisticsCollectionService/internal/metrics"
+	"StatisticsCollectionService/internal/ratelimit"
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/requestid"
+	"StatisticsCollectionService/internal/services"
+	"StatisticsCollectionService/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	httpSwagger "github.com/swaggo/http-swagger"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+)
+
+// Фоновая задача, выполняемая до отмены контекста
+type job func(ctx context.Context)
+
+// Собранный сервис
+type App struct {
+	cfg     *config.Config
+	logger  *slog.Logger
+	storage *Storage
+	service *services.Service
+	server  *http.Server
+	buffer  *repository.BatchingRepository
+	metrics *metrics.Metrics
+	health  *health.Checker
+	jobs    []job
+
+	// Ограничители частоты запросов по классам маршрутов
+	limiters map[string]*ratelimit.Limiter
+
+	// gRPC API; nil, если выключен
+	grpcServer   *grpc.Server
+	grpcAPI      *grpcapi.Server
+	grpcHealth   *grpchealth.Server
+	grpcListener net.Listener
+
+	// Контекст обрабатываемых запросов; отменяется, если они не успели
+	// завершиться за время остановки сервиса
+	requests       context.Context
+	cancelRequests context.CancelFunc
+
+	listener net.Listener
+	errs     chan error
+	cancel   context.CancelFunc
+	running  sync.WaitGroup
+}
+
+// Конструктор для сборки сервиса по конфигурации. При ошибке все уже
+// открытые ресурсы закрываются.
+func New(cfg *config.Config) (*App, error) {
+	storage, err := OpenStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := &App{cfg: cfg, logger: slog.Default(), storage: storage, errs: make(chan error, 1)}
+	if err := a.init(); err != nil {
+		storage.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *App) init() error {
+	cfg := a.cfg
+	if cluster := a.storage.Cluster; cluster != nil {
+		a.jobs = append(a.jobs, func(ctx context.Context) {
+			cluster.Run(ctx, cfg.Database.ReplicaCheckInterval, cfg.Database.ConnectTimeout)
+		})
+	}
+	if cfg.Retention.Enabled {
+		if a.storage.DB == nil {
+			return fmt.Errorf("retention is not supported by the %q storage driver", cfg.Storage.Driver)
+		}
+		archiver, err := NewArchiver(a.storage.DB, cfg.Retention)
+		if err != nil {
+			return err
+		}
+		a.jobs = append(a.jobs, func(ctx context.Context) { archiver.Run(ctx, cfg.Retention.Interval) })
+	}
+	if cfg.Partitions.Enabled && cfg.Storage.Driver == config.StoragePostgres {
+		manager := NewPartitionManager(a.storage.DB, cfg.Partitions)
+		a.jobs = append(a.jobs, func(ctx context.Context) { manager.Run(ctx, cfg.Partitions.Interval) })
+	}
+
+	repo := a.storage.Repository
+	if cfg.WriteBuffer.Enabled {
+		a.buffer = repository.NewBatchingRepository(repo, repository.BatchOptions{
+			MaxBatch:      cfg.WriteBuffer.BatchSize,
+			BufferSize:    cfg.WriteBuffer.BufferSize,
+			FlushInterval: cfg.WriteBuffer.FlushInterval,
+			FlushTimeout:  cfg.WriteBuffer.FlushTimeout,
+		})
+		repo = a.buffer
+	}
+	var cache *repository.CachingRepository
+	if cfg.Cache.Enabled {
+		cache = repository.NewCachingRepository(repo, cfg.Cache.Size, cfg.Cache.TTL)
+		repo = cache
+	}
+	repo = logging.Repository(repo, a.logger)
+	if cfg.Features.Metrics {
+		a.metrics = metrics.New(cfg.Metrics)
+		if a.storage.DB != nil {
+			a.metrics.RegisterDB(a.storage.DB, "primary")
+		}
+		if a.storage.Cluster != nil {
+			for i, replica := range a.storage.Cluster.Replicas() {
+				a.metrics.RegisterDB(replica, fmt.Sprintf("replica%d", i))
+			}
+		}
+		if cache != nil {
+			a.metrics.RegisterCache(cache)
+		}
+		// Декоратор снаружи кэша и буфера измеряет время, которое видит сервис
+		repo = a.metrics.Repository(repo)
+	}
+	a.service = services.NewService(repo)
+
+	checker, err := a.healthChecker()
+	if err != nil {
+		return err
+	}
+	a.health = checker
+
+	if cfg.RateLimit.Enabled {
+		a.limiters = map[string]*ratelimit.Limiter{
+			api.RateLimitRead:  ratelimit.New(ratelimit.Limit{Rate: cfg.RateLimit.ReadRate, Burst: cfg.RateLimit.ReadBurst}),
+			api.RateLimitWrite: ratelimit.New(ratelimit.Limit{Rate: cfg.RateLimit.WriteRate, Burst: cfg.RateLimit.WriteBurst}),
+		}
+		for class, limiter := range a.limiters {
+			if a.metrics != nil {
+				a.metrics.RegisterRateLimiter(limiter, class)
+			}
+			a.jobs = append(a.jobs, func(ctx context.Context) { limiter.Run(ctx, cfg.RateLimit.CleanupInterval) })
+		}
+	}
+
+	keys, err := api.ParseAPIKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		return fmt.Errorf("auth.api_keys: %w", err)
+	}
+	if cfg.GRPC.Enabled {
+		a.initGRPC(keys)
+	}
+	a.requests, a.cancelRequests = context.WithCancel(context.Background())
+	a.server = &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      a.routes(keys),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return a.requests },
+	}
+	return nil
+}
+
+// Метод создания маршрутов HTTP API. Маршруты API доступны с префиксом
+// /api/v1 и по прежним путям без префикса, которые считаются устаревшими.
+func (a *App) routes(keys api.APIKeys) http.Handler {
+	timeouts := a.cfg.Timeouts
+	router := api.NewRouter()
+	router.Handle(api.Route{Method: http.MethodGet, Path: "/healthz", Handler: a.health.LivenessHandler()})
+	router.Handle(api.Route{Method: http.MethodGet, Path: "/readyz", Handler: a.health.ReadinessHandler()})
+	router.HandleV1(api.Route{
+		Method:    http.MethodGet,
+		Path:      "/orderbook/get",
+		Handler:   api.WithTimeout(timeouts.For(timeouts.GetOrderBook), api.GetOrderBookHandler(a.service)),
+		Auth:      true,
+		RateLimit: api.RateLimitRead,
+	})
+	router.HandleV1(api.Route{
+		Method:    http.MethodPost,
+		Path:      "/orderbook/save",
+		Handler:   api.WithTimeout(timeouts.For(timeouts.SaveOrderBook), api.SaveOrderBookHandler(a.service)),
+		Auth:      true,
+		RateLimit: api.RateLimitWrite,
+	})
+	router.HandleV1(api.Route{
+		Method:    http.MethodGet,
+		Path:      "/orderhistory/get",
+		Handler:   api.WithTimeout(timeouts.For(timeouts.GetOrderHistory), api.GetOrderHistoryHandler(a.service)),
+		Auth:      true,
+		RateLimit: api.RateLimitRead,
+	})
+	router.HandleV1(api.Route{
+		Method:    http.MethodPost,
+		Path:      "/order/save",
+		Handler:   api.WithTimeout(timeouts.For(timeouts.SaveOrder), api.SaveOrderHandler(a.service)),
+		Auth:      true,
+		RateLimit: api.RateLimitWrite,
+	})
+
+	// Swagger endpoint
+	if a.cfg.Features.Swagger {
+		router.Handle(api.Route{Method: http.MethodGet, Path: "/swagger/", Handler: httpSwagger.WrapHandler})
+	}
+
+	router.Use(
+		requestid.Middleware,
+		api.ReadYourWrites,
+		func(next http.Handler) http.Handler { return tracing.Middleware(api.RoutePattern, next) },
+		func(next http.Handler) http.Handler { return logging.Middleware(a.logger, api.RoutePattern, next) },
+	)
+	if a.metrics != nil {
+		// Метки метрик содержат имена бирж и клиентов, поэтому метрики требуют ключа
+		router.Handle(api.Route{Method: http.MethodGet, Path: "/metrics", Handler: a.metrics.Handler(), Auth: true})
+		router.Use(func(next http.Handler) http.Handler { return a.metrics.Middleware(api.RoutePattern, next) })
+	}
+	if a.cfg.Features.Compression {
+		router.Use(api.Compress)
+	}
+	router.Use(api.Authenticate(keys))
+	if a.limiters != nil {
+		var limited func(r *http.Request, class string)
+		if a.metrics != nil {
+			limited = func(r *http.Request, class string) { a.metrics.RateLimited(api.RoutePattern(r), class) }
+		}
+		router.Use(api.RateLimit(a.limiters, limited))
+	}
+	return router
+}
+
+// Метод для получения обработчика HTTP-запросов сервиса
+func (a *App) Handler() http.Handler {
+	return a.server.Handler
+}
+
+// Метод для запуска сервиса: открывает порты HTTP и gRPC, начинает обработку запросов
+// и запускает фоновые задачи. Ошибки работы сервера доступны через Errors.
+func (a *App) Start() error {
+	if a.listener != nil {
+		return errors.New("app is already started")
+	}
+	listener, err := net.Listen("tcp", a.cfg.Server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", a.cfg.Server.Addr, err)
+	}
+	if a.grpcServer != nil {
+		grpcListener, err := net.Listen("tcp", a.cfg.GRPC.Addr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("listen on %s: %w", a.cfg.GRPC.Addr, err)
+		}
+		a.serveGRPC(grpcListener)
+	}
+	a.listener = listener
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	for _, run := range a.jobs {
+		a.running.Add(1)
+		go func(run job) {
+			defer a.running.Done()
+			run(ctx)
+		}(run)
+	}
+
+	go func() {
+		if err := a.server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			a.errs <- err
+		}
+	}()
+	a.logger.Info("Server started", "addr", listener.Addr().String())
+	return nil
+}
+
+// Метод для получения адреса, на котором сервис принимает запросы.
+// Полезен, если в настройках указан порт 0.
+func (a *App) Addr() string {
+	if a.listener == nil {
+		return a.cfg.Server.Addr
+	}
+	return a.listener.Addr().String()
+}
+
+// Метод для получения канала с ошибкой, остановившей HTTP- или gRPC-сервер
+func (a *App) Errors() <-chan error {
+	return a.errs
+}
+
+// Метод для остановки сервиса. Проверка готовности переводится в состояние
+// остановки, через server.drain_delay сервер перестаёт принимать соединения
+// и дожидается обрабатываемых запросов, затем буфер записи сбрасывает
+// накопленные ордера, фоновые задачи останавливаются и хранилище закрывается.
+// Запросы и задачи, не успевшие завершиться до отмены ctx, прерываются; на
+// запись буфера дополнительно отводится write_buffer.drain_timeout.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+	a.health.Drain()
+	if a.grpcHealth != nil {
+		a.grpcHealth.Shutdown()
+	}
+	if a.listener != nil {
+		if delay := a.cfg.Server.DrainDelay; delay > 0 {
+			a.logger.Info("Shutting down, waiting before closing the listener", "delay", delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+		}
+		a.logger.Info("Shutting down, draining in-flight requests")
+		if err := a.server.Shutdown(ctx); err != nil {
+			a.cancelRequests()
+			a.server.Close()
+			errs = append(errs, fmt.Errorf("drain requests: %w", err))
+		}
+		if a.grpcListener != nil {
+			if err := a.stopGRPC(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("drain grpc requests: %w", err))
+			}
+		}
+	}
+	a.cancelRequests()
+
+	if a.buffer != nil {
+		// Буфер получает собственное время на запись: если ожидание запросов
+		// исчерпало ctx, хранилище иначе закрылось бы во время записи пакета
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.cfg.WriteBuffer.DrainTimeout)
+		err := a.buffer.Close(flushCtx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("flush write buffer: %w", err))
+		}
+	}
+
+	if a.listener != nil {
+		a.cancel()
+		stopped := make(chan struct{})
+		go func() {
+			a.running.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("stop background jobs: %w", ctx.Err()))
+		}
+	}
+
+	if err := a.storage.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close storage: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	a.logger.Info("Shutdown complete")
+	return nil
+}
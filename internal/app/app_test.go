@@ -0,0 +1,639 @@
+package app
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/health"
+	"StatisticsCollectionService/internal/migrations"
+	"StatisticsCollectionService/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func testConfig(driver string) *config.Config {
+	cfg := config.Default()
+	cfg.Server.Addr = "127.0.0.1:0"
+	cfg.Storage.Driver = driver
+	cfg.Features.Swagger = false
+	return cfg
+}
+
+// Функция запуска сервиса на случайном порту; возвращает базовый адрес
+func startApp(t *testing.T, cfg *config.Config) string {
+	a, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, a.Start())
+	t.Cleanup(func() {
+		// Соединения клиента без запросов сервер считает активными при остановке
+		http.DefaultClient.CloseIdleConnections()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, a.Shutdown(ctx))
+	})
+	return "http://" + a.Addr()
+}
+
+func post(t *testing.T, url string, body interface{}) *http.Response {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func get(t *testing.T, url string, body, result interface{}) *http.Response {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(http.MethodGet, url, reader)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if result != nil && resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+	}
+	return resp
+}
+
+// Сценарий сохранения и чтения данных через HTTP API
+func exercise(t *testing.T, base string) {
+	book := []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}, {Price: 50500, BaseQty: 0.2}}
+	resp := post(t, base+"/orderbook/save", map[string]interface{}{"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": book})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var gotBook []*models.DepthOrder
+	resp = get(t, base+"/orderbook/get?exchange_name=Binance&pair=BTC/USDT", nil, &gotBook)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, book, gotBook)
+
+	placed := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	order := models.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "order1", Pair: "BTC/USDT", Side: "buy", Type: "limit", BaseQty: 1, Price: 10, TimePlaced: placed}
+	resp = post(t, base+"/order/save", order)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var history []*models.HistoryOrder
+	resp = get(t, base+"/orderhistory/get", models.Client{ClientName: "John Doe"}, &history)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, history, 1)
+	assert.Equal(t, "order1", history[0].Label)
+	assert.True(t, placed.Equal(history[0].TimePlaced))
+
+	resp = get(t, base+"/orderbook/get?exchange_name=Binance&pair=ETH/USDT", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+}
+
+func TestApp_Memory(t *testing.T) {
+	exercise(t, startApp(t, testConfig(config.StorageMemory)))
+}
+
+func TestApp_MemoryWithBufferAndCache(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.WriteBuffer.Enabled = true
+	cfg.Cache.Enabled = true
+	exercise(t, startApp(t, cfg))
+}
+
+// Функция создания схемы в базе SQLite из настроек
+func migrateSQLite(t *testing.T, cfg *config.Config) {
+	conn, err := db.OpenSQLite(cfg.Storage)
+	require.NoError(t, err)
+	migrator, err := migrations.NewMigrator(conn, migrations.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+}
+
+func TestApp_SQLite(t *testing.T) {
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	migrateSQLite(t, cfg)
+
+	exercise(t, startApp(t, cfg))
+}
+
+func TestApp_IsolatedInstances(t *testing.T) {
+	first := startApp(t, testConfig(config.StorageMemory))
+	second := startApp(t, testConfig(config.StorageMemory))
+	assert.NotEqual(t, first, second)
+
+	resp := post(t, first+"/orderbook/save", map[string]interface{}{"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": []*models.DepthOrder{{Price: 1, BaseQty: 1}}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = get(t, second+"/orderbook/get?exchange_name=Binance&pair=BTC/USDT", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestNew_Errors(t *testing.T) {
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	_, err := New(cfg)
+	assert.ErrorContains(t, err, "migrate up", "pending migrations must not be fatal to the process")
+
+	cfg = testConfig(config.StorageMemory)
+	cfg.Retention.Enabled = true
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestApp_ShutdownWithoutStart(t *testing.T) {
+	a, err := New(testConfig(config.StorageMemory))
+	require.NoError(t, err)
+	assert.NoError(t, a.Shutdown(context.Background()))
+}
+
+func TestApp_ShutdownDrainsInFlightRequests(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.WriteBuffer.Enabled = true
+	a, err := New(cfg)
+	require.NoError(t, err)
+
+	// Обработчик задерживается до сигнала, имитируя долгий запрос
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := a.server.Handler
+	a.server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		handler.ServeHTTP(w, r)
+	})
+	require.NoError(t, a.Start())
+	base := "http://" + a.Addr()
+
+	order := models.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "in-flight", Pair: "BTC/USDT", TimePlaced: time.Now()}
+	status := make(chan int, 1)
+	go func() {
+		data, _ := json.Marshal(order)
+		resp, err := http.Post(base+"/order/save", "application/json", bytes.NewReader(data))
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-entered
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- a.Shutdown(ctx)
+	}()
+
+	// Новые соединения не принимаются, пока идёт остановка
+	require.Eventually(t, func() bool {
+		_, err := http.Get(base + "/orderbook/get")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-status)
+	assert.NoError(t, <-shutdown)
+
+	history, err := a.storage.Repository.GetOrderHistory(context.Background(), &models.Client{ClientName: "John Doe"})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "in-flight", history[0].Label)
+}
+
+func TestApp_ShutdownTimeoutCancelsRequests(t *testing.T) {
+	a, err := New(testConfig(config.StorageMemory))
+	require.NoError(t, err)
+
+	entered := make(chan struct{})
+	cancelled := make(chan struct{})
+	a.server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-r.Context().Done()
+		close(cancelled)
+	})
+	require.NoError(t, a.Start())
+	go http.Get("http://" + a.Addr() + "/orderbook/get")
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, a.Shutdown(ctx), context.DeadlineExceeded)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("request context was not cancelled after the shutdown deadline")
+	}
+}
+
+func TestApp_ShutdownFlushesBufferAfterDeadline(t *testing.T) {
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	migrateSQLite(t, cfg)
+	// Пакет записывается только при остановке буфера
+	cfg.WriteBuffer.Enabled = true
+	cfg.WriteBuffer.FlushInterval = time.Hour
+	a, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, a.Start())
+
+	order := models.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "buffered", Pair: "BTC/USDT", TimePlaced: time.Now()}
+	go func() {
+		data, _ := json.Marshal(order)
+		if resp, err := http.Post("http://"+a.Addr()+"/api/v1/order/save", "application/json", bytes.NewReader(data)); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	require.Eventually(t, func() bool { return a.buffer.Stats().Pending == 1 }, time.Second, 5*time.Millisecond)
+
+	// Ожидание запроса, ждущего записи пакета, исчерпывает отведённое на остановку время
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = a.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, err.Error(), "flush write buffer")
+
+	reopened, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { reopened.Shutdown(context.Background()) })
+	history, err := reopened.storage.Repository.GetOrderHistory(context.Background(), &models.Client{ClientName: "John Doe"})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "buffered", history[0].Label)
+}
+
+func TestApp_Health(t *testing.T) {
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	cfg.WriteBuffer.Enabled = true
+	conn, err := db.OpenSQLite(cfg.Storage)
+	require.NoError(t, err)
+	migrator, err := migrations.NewMigrator(conn, migrations.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	base := startApp(t, cfg)
+
+	var report health.Report
+	resp := get(t, base+"/readyz", nil, &report)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.ElementsMatch(t, []string{"database", "migrations", "write_buffer"}, keys(report.Checks))
+	assert.Equal(t, fmt.Sprintf("version %d", migrator.Latest()), report.Checks["migrations"].Detail)
+
+	// Откат миграции делает сервис неготовым, но не мёртвым
+	_, err = migrator.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	report = health.Report{}
+	resp = get(t, base+"/readyz", nil, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp = get(t, base+"/healthz", nil, &report)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, health.StatusOK, report.Status)
+}
+
+func TestApp_NotReadyDuringDrain(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.Server.DrainDelay = 200 * time.Millisecond
+	a, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, a.Start())
+	base := "http://" + a.Addr()
+
+	resp := get(t, base+"/readyz", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	http.DefaultClient.CloseIdleConnections()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- a.Shutdown(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		var report health.Report
+		resp := get(t, base+"/readyz", nil, &report)
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, <-shutdown)
+}
+
+func keys(m map[string]health.CheckResult) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+func TestApp_Metrics(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.Cache.Enabled = true
+	base := startApp(t, cfg)
+	exercise(t, base)
+
+	resp, err := http.Get(base + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	for _, line := range []string{
+		`stats_http_requests_total{method="POST",route="/orderbook/save",status="200"} 1`,
+		`stats_http_requests_total{method="GET",route="/orderbook/get",status="404"} 1`,
+		`stats_orders_ingested_total{exchange="Binance",pair="BTC/USDT"} 1`,
+		`stats_repository_call_duration_seconds_count{method="SaveOrder",result="ok"} 1`,
+		`stats_order_book_age_seconds{exchange="Binance",pair="BTC/USDT"}`,
+		`stats_cache_hits_total`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+
+	cfg = testConfig(config.StorageMemory)
+	cfg.Features.Metrics = false
+	resp = get(t, startApp(t, cfg)+"/metrics", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestApp_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	migrateSQLite(t, cfg)
+	base := startApp(t, cfg)
+	order := models.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "order1", Pair: "BTC/USDT", Side: "buy", Type: "limit", BaseQty: 1, Price: 10, TimePlaced: time.Now()}
+	assert.Equal(t, http.StatusOK, post(t, base+"/order/save", order).StatusCode)
+
+	data, err := json.Marshal(models.Client{ClientName: "John Doe"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, base+"/orderhistory/get", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Spans запроса /orderhistory/get: HTTP → Service → SQL
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" {
+			spans[span.Name()] = span
+		}
+	}
+	server, ok := spans["GET /orderhistory/get"]
+	require.True(t, ok, "server span not recorded")
+	service, ok := spans["Service.GetOrderHistory"]
+	require.True(t, ok, "service span not recorded")
+	query, ok := spans["SELECT"]
+	require.True(t, ok, "query span not recorded")
+
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), service.Parent().SpanID())
+	assert.Equal(t, service.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Contains(t, service.Attributes(), attribute.String("client", "John Doe"))
+	assert.Contains(t, query.Attributes(), attribute.String("db.system", "sqlite"))
+}
+
+func TestApp_Routes(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.Auth.APIKeys = []string{"collector-1=abc"}
+	base := startApp(t, cfg)
+
+	request := func(method, path, key string) *http.Response {
+		req, err := http.NewRequest(method, base+path, http.NoBody)
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set(api.APIKeyHeader, key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := request(http.MethodGet, "/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT", "abc")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+
+	resp = request(http.MethodGet, "/orderbook/get?exchange_name=Binance&pair=BTC/USDT", "abc")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+
+	resp = request(http.MethodGet, "/api/v1/order/save", "abc")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "POST", resp.Header.Get("Allow"))
+	resp = request(http.MethodPost, "/orderhistory/get", "abc")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET, HEAD", resp.Header.Get("Allow"))
+
+	resp = request(http.MethodGet, "/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = request(http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = request(http.MethodGet, "/metrics", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = request(http.MethodGet, "/metrics", "abc")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestApp_ContentNegotiation(t *testing.T) {
+	base := startApp(t, testConfig(config.StorageMemory))
+
+	orderBook := make([]map[string]float64, 100)
+	for i := range orderBook {
+		orderBook[i] = map[string]float64{"price": 50000 + float64(i), "base_qty": 0.1}
+	}
+	body, err := msgpack.Marshal(map[string]any{"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": orderBook})
+	require.NoError(t, err)
+	resp, err := http.Post(base+"/api/v1/orderbook/save", "application/msgpack", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, base+"/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/x-protobuf")
+	req.Header.Set("Accept-Encoding", "zstd")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, api.ContentTypeProtobuf, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "zstd", resp.Header.Get("Content-Encoding"))
+
+	decoder, err := zstd.NewReader(resp.Body)
+	require.NoError(t, err)
+	defer decoder.Close()
+	data, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	var message statsv1.GetOrderBookResponse
+	require.NoError(t, proto.Unmarshal(data, &message))
+	assert.Len(t, message.GetOrderBook(), len(orderBook))
+}
+
+func TestApp_ConditionalGet(t *testing.T) {
+	cfg := testConfig(config.StorageSQLite)
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "stats.db")
+	migrateSQLite(t, cfg)
+	base := startApp(t, cfg)
+
+	resp := post(t, base+"/api/v1/orderbook/save", map[string]interface{}{
+		"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": []models.DepthOrder{{Price: 1, BaseQty: 1}},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	request := func(method, path string, headers map[string]string, body interface{}) *http.Response {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, base+path, bytes.NewReader(data))
+		require.NoError(t, err)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	const path = "/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT"
+
+	resp = request(http.MethodGet, path, nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+
+	resp = request(http.MethodGet, path, map[string]string{"If-None-Match": `"1"`}, nil)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	update := map[string]interface{}{
+		"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": []models.DepthOrder{{Price: 2, BaseQty: 2}},
+	}
+	resp = request(http.MethodPost, "/api/v1/orderbook/save", map[string]string{"If-Match": `"1"`}, update)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+	resp = request(http.MethodPost, "/api/v1/orderbook/save", map[string]string{"If-Match": `"1"`}, update)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp = request(http.MethodGet, path, map[string]string{"If-None-Match": `"1"`}, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+}
+
+func TestApp_RateLimit(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.WriteRate = 0.001
+	cfg.RateLimit.WriteBurst = 1
+	base := startApp(t, cfg)
+
+	book := map[string]interface{}{"exchange_name": "Binance", "pair": "BTC/USDT", "order_book": []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}}}
+	assert.Equal(t, http.StatusOK, post(t, base+"/api/v1/orderbook/save", book).StatusCode)
+	resp := post(t, base+"/api/v1/orderbook/save", book)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	// Чтение ограничивается отдельно
+	resp = get(t, base+"/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "100", resp.Header.Get("RateLimit-Limit"))
+
+	resp, err := http.Get(base + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `stats_rate_limited_requests_total{class="write",route="/api/v1/orderbook/save"} 1`)
+	assert.Contains(t, string(body), `stats_rate_limiter_keys{class="write"} 1`)
+}
+
+func TestApp_GRPC(t *testing.T) {
+	cfg := testConfig(config.StorageMemory)
+	cfg.GRPC.Enabled = true
+	cfg.GRPC.Addr = "127.0.0.1:0"
+	a, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, a.Start())
+
+	conn, err := grpc.NewClient(a.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx := context.Background()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "stats.v1.StatsService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+
+	reflectionStream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, reflectionStream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	listed, err := reflectionStream.Recv()
+	require.NoError(t, err)
+	var names []string
+	for _, service := range listed.GetListServicesResponse().GetService() {
+		names = append(names, service.GetName())
+	}
+	assert.Contains(t, names, "stats.v1.StatsService")
+	require.NoError(t, reflectionStream.CloseSend())
+
+	// gRPC и HTTP API работают с одними данными
+	client := statsv1.NewStatsServiceClient(conn)
+	_, err = client.SaveOrderBook(ctx, &statsv1.SaveOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT", OrderBook: []*statsv1.DepthOrder{{Price: 50000, BaseQty: 0.1}}})
+	require.NoError(t, err)
+	var book []*models.DepthOrder
+	resp := get(t, "http://"+a.Addr()+"/api/v1/orderbook/get?exchange_name=Binance&pair=BTC/USDT", nil, &book)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}}, book)
+
+	httpResp, err := http.Get("http://" + a.Addr() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `stats_grpc_requests_total{code="OK",method="/stats.v1.StatsService/SaveOrderBook"} 1`)
+
+	// Открытая подписка не задерживает остановку
+	stream, err := client.SubscribeOrderBooks(ctx, &statsv1.SubscribeOrderBooksRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	http.DefaultClient.CloseIdleConnections()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, a.Shutdown(shutdownCtx))
+	assert.Less(t, time.Since(start), time.Second)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
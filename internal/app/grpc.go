@@ -0,0 +1,95 @@
+package app
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/grpcapi"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Метод создания gRPC-сервера с тем же сервисом, ключами API, ограничением
+// частоты запросов и метриками, что и у HTTP API, а также сервисами проверки
+// состояния и рефлексии
+func (a *App) initGRPC(keys api.APIKeys) {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if a.metrics != nil {
+		unary = append(unary, a.metrics.UnaryServerInterceptor())
+		stream = append(stream, a.metrics.StreamServerInterceptor())
+	}
+	unary = append(unary, grpcapi.UnaryAuthInterceptor(keys))
+	stream = append(stream, grpcapi.StreamAuthInterceptor(keys))
+	if a.limiters != nil {
+		var limited func(method, class string)
+		if a.metrics != nil {
+			limited = a.metrics.RateLimited
+		}
+		unary = append(unary, grpcapi.UnaryRateLimitInterceptor(a.limiters, limited))
+		stream = append(stream, grpcapi.StreamRateLimitInterceptor(a.limiters, limited))
+	}
+
+	a.grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	a.grpcAPI = grpcapi.NewServer(a.service, a.cfg.Timeouts)
+	statsv1.RegisterStatsServiceServer(a.grpcServer, a.grpcAPI)
+
+	// Состояние устанавливается при запуске и снимается при остановке
+	a.grpcHealth = grpchealth.NewServer()
+	a.grpcHealth.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	a.grpcHealth.SetServingStatus(statsv1.StatsService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(a.grpcServer, a.grpcHealth)
+
+	if a.cfg.GRPC.Reflection {
+		reflection.Register(a.grpcServer)
+	}
+}
+
+// Метод запуска gRPC-сервера на уже открытом порту
+func (a *App) serveGRPC(listener net.Listener) {
+	a.grpcListener = listener
+	a.grpcHealth.Resume()
+	go func() {
+		if err := a.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			// Канал ошибок рассчитан на одну ошибку; первой достаточно для остановки
+			select {
+			case a.errs <- fmt.Errorf("grpc: %w", err):
+			default:
+			}
+		}
+	}()
+	a.logger.Info("gRPC server started", "addr", listener.Addr().String())
+}
+
+// Метод остановки gRPC-сервера: подписки завершаются, унарные вызовы
+// дожидаются завершения до отмены ctx, после чего прерываются
+func (a *App) stopGRPC(ctx context.Context) error {
+	a.grpcAPI.Close()
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		a.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// Метод для получения адреса, на котором gRPC-сервер принимает запросы;
+// пустая строка — gRPC-сервер не запущен
+func (a *App) GRPCAddr() string {
+	if a.grpcListener == nil {
+		return ""
+	}
+	return a.grpcListener.Addr().String()
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/health"
+	"StatisticsCollectionService/internal/migrations"
+	"context"
+	"fmt"
+)
+
+// Метод создания проверок готовности для настроенного хранилища и буфера записи
+func (a *App) healthChecker() (*health.Checker, error) {
+	checker := health.NewChecker(a.cfg.Health.Timeout)
+
+	if conn := a.storage.DB; conn != nil {
+		checker.AddReadiness("database", func(ctx context.Context) (string, error) {
+			return "", conn.PingContext(ctx)
+		})
+
+		dialect := migrations.Postgres
+		if a.cfg.Storage.Driver == config.StorageSQLite {
+			dialect = migrations.SQLite
+		}
+		migrator, err := migrations.NewMigrator(conn, dialect)
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: %w", err)
+		}
+		checker.AddReadiness("migrations", func(ctx context.Context) (string, error) {
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("version %d", version)
+			if version < migrator.Latest() {
+				return detail, fmt.Errorf("%w: required version %d", migrations.ErrSchemaOutdated, migrator.Latest())
+			}
+			return detail, nil
+		})
+	}
+
+	// Недоступность реплик не мешает работе: чтение переходит на основной сервер
+	if cluster := a.storage.Cluster; cluster != nil {
+		checker.AddReadiness("replicas", func(ctx context.Context) (string, error) {
+			statuses := cluster.Status()
+			healthy := 0
+			for _, s := range statuses {
+				if s.Healthy {
+					healthy++
+				}
+			}
+			return fmt.Sprintf("%d/%d healthy", healthy, len(statuses)), nil
+		})
+	}
+
+	if buffer := a.buffer; buffer != nil {
+		highWater := a.cfg.Health.WriteBufferHighWater
+		checker.AddReadiness("write_buffer", func(ctx context.Context) (string, error) {
+			stats := buffer.Stats()
+			detail := fmt.Sprintf("%d/%d pending, peak %d", stats.Pending, stats.Capacity, stats.HighWater)
+			if float64(stats.Pending) >= highWater*float64(stats.Capacity) {
+				return detail, fmt.Errorf("write buffer is above the high-water mark of %.0f%%", highWater*100)
+			}
+			return detail, nil
+		})
+	}
+	return checker, nil
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/partitions"
+	"StatisticsCollectionService/internal/retention"
+	"database/sql"
+	"fmt"
+)
+
+// Функция создания менеджера секций по настройкам
+func NewPartitionManager(db *sql.DB, cfg config.PartitionsConfig) *partitions.Manager {
+	return partitions.NewManager(db, partitions.Options{
+		Premake:         cfg.Premake,
+		Retention:       cfg.RetentionMonths,
+		RetentionAction: cfg.RetentionAction,
+	})
+}
+
+// Функция создания архиватора по настройкам сроков хранения
+func NewArchiver(db *sql.DB, cfg config.RetentionConfig) (*retention.Archiver, error) {
+	clients, err := retention.ParseClientOverrides(cfg.Clients)
+	if err != nil {
+		return nil, fmt.Errorf("load retention policy: %w", err)
+	}
+	policy := retention.Policy{OrderBooks: cfg.OrderBooks, OrderHistory: cfg.OrderHistory, Clients: clients}
+	return retention.NewArchiver(db, policy, cfg.ArchiveDir, cfg.BatchSize), nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/migrations"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Хранилище данных сервиса: подключение к базе и репозиторий поверх него
+type Storage struct {
+	// Подключение к базе данных; nil для хранилища в памяти
+	DB         *sql.DB
+	Repository repository.Repository
+	// Реплики PostgreSQL; nil, если они не настроены
+	Cluster *db.Cluster
+}
+
+// Функция открытия хранилища в соответствии с настройками. Если включена
+// проверка схемы, хранилище с непримененными миграциями не открывается.
+func OpenStorage(cfg *config.Config) (*Storage, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageMemory:
+		slog.Warn("Using in-memory storage, data will be lost on restart")
+		return &Storage{Repository: repository.NewInMemoryRepository()}, nil
+	case config.StorageSQLite:
+		sqliteDB, err := db.OpenSQLite(cfg.Storage)
+		if err != nil {
+			return nil, err
+		}
+		storage := &Storage{DB: sqliteDB, Repository: repository.NewSQLiteRepository(sqliteDB)}
+		if cfg.Features.SchemaCheck {
+			if err := checkSchema(sqliteDB, migrations.SQLite); err != nil {
+				storage.Close()
+				return nil, err
+			}
+		}
+		return storage, nil
+	default:
+		primary, err := db.Open(cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		storage := &Storage{DB: primary, Repository: repository.NewPostgresRepository(primary)}
+		if cfg.Features.SchemaCheck {
+			if err := checkSchema(primary, migrations.Postgres); err != nil {
+				storage.Close()
+				return nil, err
+			}
+		}
+		if len(cfg.Database.Replicas) > 0 {
+			replicas, err := db.OpenReplicas(cfg.Database)
+			if err != nil {
+				storage.Close()
+				return nil, err
+			}
+			storage.Cluster = db.NewCluster(primary, replicas)
+			storage.Repository = repository.NewReplicatedPostgresRepository(storage.Cluster)
+		}
+		return storage, nil
+	}
+}
+
+// Метод для закрытия подключений к базе данных и репликам
+func (s *Storage) Close() error {
+	var errs []error
+	if s.Cluster != nil {
+		errs = append(errs, s.Cluster.Close())
+	}
+	if s.DB != nil {
+		errs = append(errs, s.DB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Функция проверки, что схема базы данных не отстает от кода
+func checkSchema(conn *sql.DB, dialect migrations.Dialect) error {
+	migrator, err := migrations.NewMigrator(conn, dialect)
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		return fmt.Errorf("%w (run \"migrate up\")", err)
+	}
+	return nil
+}
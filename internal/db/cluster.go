@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Ключ контекста для запросов, требующих чтения с основного сервера
+type primaryKey struct{}
+
+// Функция, помечающая контекст так, что чтения в нём выполняются на основном
+// сервере. Используется клиентами, которым нужно сразу увидеть свою запись.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Функция проверки, требует ли контекст чтения с основного сервера
+func PrimaryRequested(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Реплика и результат последней проверки её доступности
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Состояние реплики
+type ReplicaStatus struct {
+	Index   int
+	Healthy bool
+}
+
+// Структура, распределяющая запросы между основным сервером и репликами:
+// запись всегда выполняется на основном сервере, чтение — на доступных
+// репликах по очереди, а при их отсутствии — на основном сервере.
+type Cluster struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// Конструктор для создания кластера. Реплики считаются доступными до первой проверки.
+func NewCluster(primary *sql.DB, replicas []*sql.DB) *Cluster {
+	c := &Cluster{primary: primary}
+	for _, db := range replicas {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+	return c
+}
+
+// Метод для получения подключения к основному серверу
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary
+}
+
+// Метод для получения подключений к репликам
+func (c *Cluster) Replicas() []*sql.DB {
+	replicas := make([]*sql.DB, len(c.replicas))
+	for i, r := range c.replicas {
+		replicas[i] = r.db
+	}
+	return replicas
+}
+
+// Метод для выполнения чтения. Если реплика вернула ошибку, для которой
+// unavailable возвращает true, она помечается недоступной, а чтение
+// повторяется на основном сервере.
+func (c *Cluster) Read(ctx context.Context, unavailable func(error) bool, fn func(db *sql.DB) error) error {
+	r := c.pick(ctx)
+	if r == nil {
+		return fn(c.primary)
+	}
+	err := fn(r.db)
+	if err == nil || !unavailable(err) || ctx.Err() != nil {
+		return err
+	}
+	c.markDown(r, err)
+	return fn(c.primary)
+}
+
+// Метод выбора доступной реплики по кругу; nil — читать с основного сервера
+func (c *Cluster) pick(ctx context.Context) *replica {
+	if len(c.replicas) == 0 || PrimaryRequested(ctx) {
+		return nil
+	}
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(start+uint64(i))%uint64(len(c.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) markDown(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		slog.Warn("Database replica is unavailable, reading from primary", "replica", c.index(r), "error", err)
+	}
+}
+
+func (c *Cluster) index(r *replica) int {
+	for i, candidate := range c.replicas {
+		if candidate == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Метод для проверки доступности всех реплик
+func (c *Cluster) Check(ctx context.Context, timeout time.Duration) {
+	for i, r := range c.replicas {
+		pingCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			pingCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := r.db.PingContext(pingCtx)
+		cancel()
+		if err != nil {
+			c.markDown(r, err)
+			continue
+		}
+		if !r.healthy.Swap(true) {
+			slog.Info("Database replica is available again", "replica", i)
+		}
+	}
+}
+
+// Метод для периодической проверки доступности реплик до отмены контекста
+func (c *Cluster) Run(ctx context.Context, interval, timeout time.Duration) {
+	if len(c.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Check(ctx, timeout)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Метод для получения состояния реплик
+func (c *Cluster) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, len(c.replicas))
+	for i, r := range c.replicas {
+		statuses[i] = ReplicaStatus{Index: i, Healthy: r.healthy.Load()}
+	}
+	return statuses
+}
+
+// Метод для закрытия подключений к репликам; основной сервер закрывает владелец
+func (c *Cluster) Close() error {
+	var firstErr error
+	for _, r := range c.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"StatisticsCollectionService/config"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("down")
+
+func openTestDB(t *testing.T, name string) *sql.DB {
+	cfg := config.StorageConfig{SQLitePath: filepath.Join(t.TempDir(), name), SQLiteBusyTimeout: time.Second}
+	sqliteDB, err := sql.Open("sqlite", SQLiteDSN(cfg))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDB.Close() })
+	return sqliteDB
+}
+
+func isDown(err error) bool { return errors.Is(err, errDown) }
+
+func readFrom(t *testing.T, c *Cluster, ctx context.Context) *sql.DB {
+	var used *sql.DB
+	require.NoError(t, c.Read(ctx, isDown, func(db *sql.DB) error {
+		used = db
+		return nil
+	}))
+	return used
+}
+
+func TestCluster_RoundRobin(t *testing.T) {
+	primary, first, second := openTestDB(t, "primary.db"), openTestDB(t, "first.db"), openTestDB(t, "second.db")
+	c := NewCluster(primary, []*sql.DB{first, second})
+	ctx := context.Background()
+
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		seen[readFrom(t, c, ctx)]++
+	}
+	assert.Equal(t, map[*sql.DB]int{first: 2, second: 2}, seen)
+	assert.Equal(t, primary, readFrom(t, c, WithPrimary(ctx)), "read-your-writes must use the primary")
+}
+
+func TestCluster_WithoutReplicas(t *testing.T) {
+	primary := openTestDB(t, "primary.db")
+	c := NewCluster(primary, nil)
+	assert.Equal(t, primary, readFrom(t, c, context.Background()))
+}
+
+func TestCluster_FailoverOnError(t *testing.T) {
+	primary, replica := openTestDB(t, "primary.db"), openTestDB(t, "replica.db")
+	c := NewCluster(primary, []*sql.DB{replica})
+	ctx := context.Background()
+
+	var attempts []*sql.DB
+	err := c.Read(ctx, isDown, func(db *sql.DB) error {
+		attempts = append(attempts, db)
+		if db == replica {
+			return errDown
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*sql.DB{replica, primary}, attempts)
+	assert.Equal(t, []ReplicaStatus{{Index: 0, Healthy: false}}, c.Status())
+	assert.Equal(t, primary, readFrom(t, c, ctx), "unhealthy replica must be skipped")
+
+	// Ошибки, не связанные с доступностью, не приводят к повтору
+	c.Check(ctx, time.Second)
+	otherErr := errors.New("not found")
+	calls := 0
+	err = c.Read(ctx, isDown, func(db *sql.DB) error {
+		calls++
+		return otherErr
+	})
+	assert.Equal(t, otherErr, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCluster_Check(t *testing.T) {
+	primary, healthy, broken := openTestDB(t, "primary.db"), openTestDB(t, "healthy.db"), openTestDB(t, "broken.db")
+	c := NewCluster(primary, []*sql.DB{healthy, broken})
+	broken.Close()
+
+	c.Check(context.Background(), time.Second)
+	assert.Equal(t, []ReplicaStatus{{Index: 0, Healthy: true}, {Index: 1, Healthy: false}}, c.Status())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, healthy, readFrom(t, c, context.Background()))
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"StatisticsCollectionService/config"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// Функция открытия встроенной базы данных SQLite
+func OpenSQLite(cfg config.StorageConfig) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", SQLiteDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	slog.Info("SQLite database opened", "path", cfg.SQLitePath)
+	return db, nil
+}
+
+// Функция формирования строки подключения к SQLite. Журнал WAL позволяет
+// читать параллельно с записью, а busy_timeout — дождаться блокировки записи.
+func SQLiteDSN(cfg config.StorageConfig) string {
+	query := url.Values{}
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.SQLiteBusyTimeout.Milliseconds()))
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Set("_time_format", "sqlite")
+	return "file:" + cfg.SQLitePath + "?" + query.Encode()
+}
@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Ключ метаданных с ключом API. Как и в HTTP API, ключ можно передать в
+// метаданных authorization: Bearer <ключ>.
+const apiKeyMetadata = "x-api-key"
+
+// Функция получения ключа API из метаданных запроса
+func apiKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(apiKeyMetadata); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		if token, ok := strings.CutPrefix(values[0], "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// Функция проверки ключа API. Ключ требуется для методов StatsService;
+// сервисы проверки состояния и рефлексии доступны без него. Имя владельца
+// ключа сохраняется в контексте так же, как в HTTP API.
+func authenticate(ctx context.Context, keys api.APIKeys, method string) (context.Context, error) {
+	if len(keys) == 0 {
+		return ctx, nil
+	}
+	if name, ok := keys.Lookup(apiKey(ctx)); ok {
+		return api.WithKeyName(ctx, name), nil
+	}
+	if strings.HasPrefix(method, "/"+statsv1.StatsService_ServiceDesc.ServiceName+"/") {
+		return nil, status.Error(codes.Unauthenticated, "a valid API key is required in the "+apiKeyMetadata+" metadata")
+	}
+	return ctx, nil
+}
+
+// Перехватчик проверки ключа API для унарных методов
+func UnaryAuthInterceptor(keys api.APIKeys) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, keys, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Перехватчик проверки ключа API для потоковых методов
+func StreamAuthInterceptor(keys api.APIKeys) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), keys, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// Поток с заменённым контекстом
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,48 @@
+package grpcapi
+
+import (
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/services"
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Функция преобразования ошибки сервиса в статус gRPC с кодом, соответствующим
+// её причине. Как и в HTTP API, текст внутренних ошибок клиенту не передаётся.
+func toStatus(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	var validationErr *services.ValidationError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "the request was not completed within the configured deadline")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "the request was cancelled by the client")
+	case errors.As(err, &validationErr):
+		st := status.New(codes.InvalidArgument, "one or more fields are invalid")
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message}
+		}
+		if detailed, detailsErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailsErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	case errors.Is(err, repository.ErrInvalid):
+		return status.Error(codes.InvalidArgument, "the data was rejected by the storage")
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, "the requested resource does not exist")
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.AlreadyExists, "the request conflicts with the current state of the resource")
+	case errors.Is(err, repository.ErrUnavailable):
+		return status.Error(codes.Unavailable, "the storage is temporarily unavailable, retry later")
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
@@ -0,0 +1,89 @@
+package grpcapi
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/ratelimit"
+	"context"
+	"net"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Классы ограничения частоты методов StatsService, те же, что у маршрутов
+// HTTP API. Подписки учитываются при открытии как чтение.
+var methodRateLimits = map[string]string{
+	statsv1.StatsService_GetOrderBook_FullMethodName:        api.RateLimitRead,
+	statsv1.StatsService_GetOrderHistory_FullMethodName:     api.RateLimitRead,
+	statsv1.StatsService_SubscribeOrderBooks_FullMethodName: api.RateLimitRead,
+	statsv1.StatsService_SubscribeOrders_FullMethodName:     api.RateLimitRead,
+	statsv1.StatsService_SaveOrderBook_FullMethodName:       api.RateLimitWrite,
+	statsv1.StatsService_SaveOrder_FullMethodName:           api.RateLimitWrite,
+}
+
+// Функция выбора ключа ограничения: владелец ключа API или адрес клиента,
+// как в HTTP API, поэтому оба API расходуют общее ограничение клиента
+func rateLimitKey(ctx context.Context) string {
+	if name := api.KeyNameFromContext(ctx); name != "" {
+		return "key:" + name
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// Функция проверки ограничения частоты вызова метода. Отклонённые вызовы
+// получают ResourceExhausted с RetryInfo; функция limited вызывается для
+// каждого из них.
+func allow(ctx context.Context, limiters map[string]*ratelimit.Limiter, method string, limited func(method, class string)) error {
+	class := methodRateLimits[method]
+	limiter := limiters[class]
+	if limiter == nil {
+		return nil
+	}
+	d := limiter.Allow(rateLimitKey(ctx))
+	if d.Allowed {
+		return nil
+	}
+	if limited != nil {
+		limited(method, class)
+	}
+	st := status.New(codes.ResourceExhausted, "the "+class+" rate limit is exceeded, retry later")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d.RetryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// Перехватчик ограничения частоты унарных вызовов. Должен работать после
+// UnaryAuthInterceptor, чтобы учитывать владельца ключа API.
+func UnaryRateLimitInterceptor(limiters map[string]*ratelimit.Limiter, limited func(method, class string)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, limiters, info.FullMethod, limited); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Перехватчик ограничения частоты открытия подписок. Должен работать после
+// StreamAuthInterceptor, чтобы учитывать владельца ключа API.
+func StreamRateLimitInterceptor(limiters map[string]*ratelimit.Limiter, limited func(method, class string)) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(stream.Context(), limiters, info.FullMethod, limited); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,149 @@
+// Пакет grpcapi реализует gRPC API сервиса (proto/stats/v1/stats.proto)
+// поверх того же сервисного слоя, что и HTTP API. Код пакета statsv1
+// генерируется из proto-файла командой buf generate.
+package grpcapi
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/protoconv"
+	"StatisticsCollectionService/internal/services"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Реализация StatsService
+type Server struct {
+	statsv1.UnimplementedStatsServiceServer
+
+	service  *services.Service
+	timeouts config.TimeoutsConfig
+
+	// Закрывается при остановке, чтобы завершить бесконечные подписки
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Конструктор для создания gRPC API. Время обработки запросов ограничивается
+// так же, как в HTTP API.
+func NewServer(service *services.Service, timeouts config.TimeoutsConfig) *Server {
+	return &Server{service: service, timeouts: timeouts, done: make(chan struct{})}
+}
+
+// Метод для завершения подписок перед остановкой gRPC-сервера: без него
+// GracefulStop ждал бы их бесконечно
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Функция ограничения времени обработки запроса; 0 — без ограничения
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (s *Server) GetOrderBook(ctx context.Context, req *statsv1.GetOrderBookRequest) (*statsv1.GetOrderBookResponse, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.For(s.timeouts.GetOrderBook))
+	defer cancel()
+	orderBook, err := s.service.GetOrderBook(ctx, req.GetExchangeName(), req.GetPair())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &statsv1.GetOrderBookResponse{OrderBook: protoconv.DepthOrdersToProto(orderBook)}, nil
+}
+
+func (s *Server) SaveOrderBook(ctx context.Context, req *statsv1.SaveOrderBookRequest) (*statsv1.SaveOrderBookResponse, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.For(s.timeouts.SaveOrderBook))
+	defer cancel()
+	err := s.service.SaveOrderBook(ctx, req.GetExchangeName(), req.GetPair(), protoconv.DepthOrdersFromProto(req.GetOrderBook()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &statsv1.SaveOrderBookResponse{}, nil
+}
+
+func (s *Server) GetOrderHistory(ctx context.Context, req *statsv1.GetOrderHistoryRequest) (*statsv1.GetOrderHistoryResponse, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.For(s.timeouts.GetOrderHistory))
+	defer cancel()
+	history, err := s.service.GetOrderHistory(ctx, protoconv.ClientFromProto(req.GetClient()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &statsv1.GetOrderHistoryResponse{Orders: protoconv.HistoryToProto(history)}, nil
+}
+
+func (s *Server) SaveOrder(ctx context.Context, req *statsv1.SaveOrderRequest) (*statsv1.SaveOrderResponse, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.For(s.timeouts.SaveOrder))
+	defer cancel()
+	order := protoconv.HistoryOrderFromProto(req.GetOrder())
+	var client *models.Client
+	if order != nil {
+		client = &models.Client{
+			ClientName:   order.ClientName,
+			ExchangeName: order.ExchangeName,
+			Label:        order.Label,
+			Pair:         order.Pair,
+		}
+	}
+	if err := s.service.SaveOrder(ctx, client, order); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &statsv1.SaveOrderResponse{}, nil
+}
+
+func (s *Server) SubscribeOrderBooks(req *statsv1.SubscribeOrderBooksRequest, stream statsv1.StatsService_SubscribeOrderBooksServer) error {
+	sub, err := s.service.SubscribeOrderBooks(req.GetExchangeName(), req.GetPair())
+	if err != nil {
+		return toStatus(stream.Context(), err)
+	}
+	defer sub.Close()
+	return forward(stream.Context(), s.done, sub, func(update services.OrderBookUpdate) error {
+		return stream.Send(&statsv1.OrderBookUpdate{
+			ExchangeName: update.ExchangeName,
+			Pair:         update.Pair,
+			OrderBook:    protoconv.DepthOrdersToProto(update.OrderBook),
+		})
+	})
+}
+
+func (s *Server) SubscribeOrders(req *statsv1.SubscribeOrdersRequest, stream statsv1.StatsService_SubscribeOrdersServer) error {
+	sub, err := s.service.SubscribeOrders(&models.Client{
+		ClientName:   req.GetClientName(),
+		ExchangeName: req.GetExchangeName(),
+		Pair:         req.GetPair(),
+	})
+	if err != nil {
+		return toStatus(stream.Context(), err)
+	}
+	defer sub.Close()
+	return forward(stream.Context(), s.done, sub, func(order *models.HistoryOrder) error {
+		return stream.Send(&statsv1.OrderUpdate{Order: protoconv.HistoryOrderToProto(order)})
+	})
+}
+
+// Функция пересылки событий подписки клиенту до отмены запроса, остановки
+// сервера или отключения подписки из-за переполнения очереди
+func forward[T any](ctx context.Context, done <-chan struct{}, sub *services.Subscription[T], send func(T) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return toStatus(ctx, ctx.Err())
+		case <-done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber is too slow, events were dropped")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package grpcapi
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/ratelimit"
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/services"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Функция запуска сервера в памяти; возвращает клиента и сервер
+func startServer(t *testing.T, keys api.APIKeys) (statsv1.StatsServiceClient, *Server) {
+	return startServerWithInterceptors(t,
+		[]grpc.UnaryServerInterceptor{UnaryAuthInterceptor(keys)},
+		[]grpc.StreamServerInterceptor{StreamAuthInterceptor(keys)},
+	)
+}
+
+func startServerWithInterceptors(t *testing.T, unary []grpc.UnaryServerInterceptor, stream []grpc.StreamServerInterceptor) (statsv1.StatsServiceClient, *Server) {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(services.NewService(repository.NewInMemoryRepository()), config.Default().Timeouts)
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	statsv1.RegisterStatsServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(func() {
+		server.Close()
+		grpcServer.GracefulStop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return statsv1.NewStatsServiceClient(conn), server
+}
+
+func TestServer_OrderBook(t *testing.T) {
+	client, _ := startServer(t, nil)
+	ctx := context.Background()
+
+	_, err := client.GetOrderBook(ctx, &statsv1.GetOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	book := []*statsv1.DepthOrder{{Price: 50000, BaseQty: 0.1}, {Price: 50500, BaseQty: 0.2}}
+	_, err = client.SaveOrderBook(ctx, &statsv1.SaveOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT", OrderBook: book})
+	require.NoError(t, err)
+
+	resp, err := client.GetOrderBook(ctx, &statsv1.GetOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrderBook(), 2)
+	assert.Equal(t, 50500.0, resp.GetOrderBook()[1].GetPrice())
+}
+
+func TestServer_OrderHistory(t *testing.T) {
+	client, _ := startServer(t, nil)
+	ctx := context.Background()
+
+	placed := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	order := &statsv1.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "order1", Pair: "BTC/USDT", Side: "buy", Type: "limit", BaseQty: 1, Price: 10, TimePlaced: timestamppb.New(placed)}
+	_, err := client.SaveOrder(ctx, &statsv1.SaveOrderRequest{Order: order})
+	require.NoError(t, err)
+
+	resp, err := client.GetOrderHistory(ctx, &statsv1.GetOrderHistoryRequest{Client: &statsv1.Client{ClientName: "John Doe"}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrders(), 1)
+	assert.Equal(t, "order1", resp.GetOrders()[0].GetLabel())
+	assert.True(t, placed.Equal(resp.GetOrders()[0].GetTimePlaced().AsTime()))
+
+	resp, err = client.GetOrderHistory(ctx, &statsv1.GetOrderHistoryRequest{Client: &statsv1.Client{
+		ClientName: "John Doe",
+		TimeFrom:   timestamppb.New(placed.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetOrders())
+}
+
+func TestServer_Validation(t *testing.T) {
+	client, _ := startServer(t, nil)
+	_, err := client.SaveOrder(context.Background(), &statsv1.SaveOrderRequest{Order: &statsv1.HistoryOrder{Price: -1}})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	var fields []string
+	for _, violation := range badRequest.GetFieldViolations() {
+		fields = append(fields, violation.GetField())
+	}
+	assert.Contains(t, fields, "client_name")
+	assert.Contains(t, fields, "price")
+
+	_, err = client.SaveOrder(context.Background(), &statsv1.SaveOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_SubscribeOrderBooks(t *testing.T) {
+	client, server := startServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.SubscribeOrderBooks(ctx, &statsv1.SubscribeOrderBooksRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	// Подписка создаётся на сервере асинхронно; сохраняем, пока не придёт событие
+	received := make(chan *statsv1.OrderBookUpdate, 1)
+	go func() {
+		update, err := stream.Recv()
+		if err == nil {
+			received <- update
+		}
+	}()
+	book := []*statsv1.DepthOrder{{Price: 50000, BaseQty: 0.1}}
+	var update *statsv1.OrderBookUpdate
+	require.Eventually(t, func() bool {
+		_, err := client.SaveOrderBook(ctx, &statsv1.SaveOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT", OrderBook: book})
+		require.NoError(t, err)
+		select {
+		case update = <-received:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 2*time.Second, time.Millisecond)
+	assert.Equal(t, "BTC/USDT", update.GetPair())
+	assert.Equal(t, 50000.0, update.GetOrderBook()[0].GetPrice())
+
+	// Остановка сервера завершает подписку
+	server.Close()
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_SubscribeOrders(t *testing.T) {
+	client, _ := startServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := func() (*statsv1.OrderUpdate, error) {
+		stream, err := client.SubscribeOrders(ctx, &statsv1.SubscribeOrdersRequest{})
+		require.NoError(t, err)
+		return stream.Recv()
+	}()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err := client.SubscribeOrders(ctx, &statsv1.SubscribeOrdersRequest{ClientName: "John Doe"})
+	require.NoError(t, err)
+	received := make(chan *statsv1.OrderUpdate, 1)
+	go func() {
+		update, err := stream.Recv()
+		if err == nil {
+			received <- update
+		}
+	}()
+	order := &statsv1.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Label: "order1", Pair: "BTC/USDT"}
+	require.Eventually(t, func() bool {
+		_, err := client.SaveOrder(ctx, &statsv1.SaveOrderRequest{Order: order})
+		require.NoError(t, err)
+		select {
+		case update := <-received:
+			return assert.Equal(t, "order1", update.GetOrder().GetLabel())
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 2*time.Second, time.Millisecond)
+}
+
+func TestServer_Auth(t *testing.T) {
+	client, _ := startServer(t, api.APIKeys{"abc": "collector-1"})
+	req := &statsv1.GetOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT"}
+
+	_, err := client.GetOrderBook(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "abc")
+	_, err = client.GetOrderBook(ctx, req)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer abc")
+	_, err = client.GetOrderBook(ctx, req)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stream, err := client.SubscribeOrderBooks(context.Background(), &statsv1.SubscribeOrderBooksRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	keys := api.APIKeys{"abc": "collector-1", "def": "collector-2"}
+	limiters := map[string]*ratelimit.Limiter{
+		api.RateLimitRead:  ratelimit.New(ratelimit.Limit{Rate: 0.001, Burst: 1}),
+		api.RateLimitWrite: ratelimit.New(ratelimit.Limit{Rate: 0.001, Burst: 1}),
+	}
+	var limited []string
+	onLimited := func(method, class string) { limited = append(limited, method+" "+class) }
+	client, _ := startServerWithInterceptors(t,
+		[]grpc.UnaryServerInterceptor{UnaryAuthInterceptor(keys), UnaryRateLimitInterceptor(limiters, onLimited)},
+		[]grpc.StreamServerInterceptor{StreamAuthInterceptor(keys), StreamRateLimitInterceptor(limiters, onLimited)},
+	)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "abc")
+	save := &statsv1.SaveOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT"}
+
+	_, err := client.SaveOrderBook(ctx, save)
+	require.NoError(t, err)
+	_, err = client.SaveOrderBook(ctx, save)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	assert.InDelta(t, 1000, st.Details()[0].(*errdetails.RetryInfo).GetRetryDelay().AsDuration().Seconds(), 1)
+
+	// Чтение ограничивается отдельно от записи, подписки — как чтение
+	_, err = client.GetOrderBook(ctx, &statsv1.GetOrderBookRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	stream, err := client.SubscribeOrderBooks(ctx, &statsv1.SubscribeOrderBooksRequest{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Владелец другого ключа ограничивается отдельно
+	other := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "def")
+	_, err = client.SaveOrderBook(other, save)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		statsv1.StatsService_SaveOrderBook_FullMethodName + " write",
+		statsv1.StatsService_SubscribeOrderBooks_FullMethodName + " read",
+	}, limited)
+}
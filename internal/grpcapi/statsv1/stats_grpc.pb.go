@@ -0,0 +1,333 @@
+// API сервиса сбора статистики. Повторяет методы HTTP API и дополняет их
+// подписками на обновления книг ордеров и новые ордера.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: stats/v1/stats.proto
+
+package statsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StatsService_GetOrderBook_FullMethodName        = "/stats.v1.StatsService/GetOrderBook"
+	StatsService_SaveOrderBook_FullMethodName       = "/stats.v1.StatsService/SaveOrderBook"
+	StatsService_GetOrderHistory_FullMethodName     = "/stats.v1.StatsService/GetOrderHistory"
+	StatsService_SaveOrder_FullMethodName           = "/stats.v1.StatsService/SaveOrder"
+	StatsService_SubscribeOrderBooks_FullMethodName = "/stats.v1.StatsService/SubscribeOrderBooks"
+	StatsService_SubscribeOrders_FullMethodName     = "/stats.v1.StatsService/SubscribeOrders"
+)
+
+// StatsServiceClient is the client API for StatsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StatsServiceClient interface {
+	// Получить книгу ордеров для биржи и пары валют
+	GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*GetOrderBookResponse, error)
+	// Сохранить книгу ордеров для биржи и пары валют
+	SaveOrderBook(ctx context.Context, in *SaveOrderBookRequest, opts ...grpc.CallOption) (*SaveOrderBookResponse, error)
+	// Получить историю ордеров клиента
+	GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*GetOrderHistoryResponse, error)
+	// Сохранить ордер клиента
+	SaveOrder(ctx context.Context, in *SaveOrderRequest, opts ...grpc.CallOption) (*SaveOrderResponse, error)
+	// Подписаться на сохранение книг ордеров биржи и пары валют
+	SubscribeOrderBooks(ctx context.Context, in *SubscribeOrderBooksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderBookUpdate], error)
+	// Подписаться на новые ордера клиента
+	SubscribeOrders(ctx context.Context, in *SubscribeOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderUpdate], error)
+}
+
+type statsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStatsServiceClient(cc grpc.ClientConnInterface) StatsServiceClient {
+	return &statsServiceClient{cc}
+}
+
+func (c *statsServiceClient) GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*GetOrderBookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderBookResponse)
+	err := c.cc.Invoke(ctx, StatsService_GetOrderBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) SaveOrderBook(ctx context.Context, in *SaveOrderBookRequest, opts ...grpc.CallOption) (*SaveOrderBookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveOrderBookResponse)
+	err := c.cc.Invoke(ctx, StatsService_SaveOrderBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*GetOrderHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderHistoryResponse)
+	err := c.cc.Invoke(ctx, StatsService_GetOrderHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) SaveOrder(ctx context.Context, in *SaveOrderRequest, opts ...grpc.CallOption) (*SaveOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveOrderResponse)
+	err := c.cc.Invoke(ctx, StatsService_SaveOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) SubscribeOrderBooks(ctx context.Context, in *SubscribeOrderBooksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderBookUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[0], StatsService_SubscribeOrderBooks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeOrderBooksRequest, OrderBookUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeOrderBooksClient = grpc.ServerStreamingClient[OrderBookUpdate]
+
+func (c *statsServiceClient) SubscribeOrders(ctx context.Context, in *SubscribeOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[1], StatsService_SubscribeOrders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeOrdersRequest, OrderUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeOrdersClient = grpc.ServerStreamingClient[OrderUpdate]
+
+// StatsServiceServer is the server API for StatsService service.
+// All implementations must embed UnimplementedStatsServiceServer
+// for forward compatibility.
+type StatsServiceServer interface {
+	// Получить книгу ордеров для биржи и пары валют
+	GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error)
+	// Сохранить книгу ордеров для биржи и пары валют
+	SaveOrderBook(context.Context, *SaveOrderBookRequest) (*SaveOrderBookResponse, error)
+	// Получить историю ордеров клиента
+	GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*GetOrderHistoryResponse, error)
+	// Сохранить ордер клиента
+	SaveOrder(context.Context, *SaveOrderRequest) (*SaveOrderResponse, error)
+	// Подписаться на сохранение книг ордеров биржи и пары валют
+	SubscribeOrderBooks(*SubscribeOrderBooksRequest, grpc.ServerStreamingServer[OrderBookUpdate]) error
+	// Подписаться на новые ордера клиента
+	SubscribeOrders(*SubscribeOrdersRequest, grpc.ServerStreamingServer[OrderUpdate]) error
+	mustEmbedUnimplementedStatsServiceServer()
+}
+
+// UnimplementedStatsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStatsServiceServer struct{}
+
+func (UnimplementedStatsServiceServer) GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
+}
+func (UnimplementedStatsServiceServer) SaveOrderBook(context.Context, *SaveOrderBookRequest) (*SaveOrderBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveOrderBook not implemented")
+}
+func (UnimplementedStatsServiceServer) GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*GetOrderHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderHistory not implemented")
+}
+func (UnimplementedStatsServiceServer) SaveOrder(context.Context, *SaveOrderRequest) (*SaveOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveOrder not implemented")
+}
+func (UnimplementedStatsServiceServer) SubscribeOrderBooks(*SubscribeOrderBooksRequest, grpc.ServerStreamingServer[OrderBookUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeOrderBooks not implemented")
+}
+func (UnimplementedStatsServiceServer) SubscribeOrders(*SubscribeOrdersRequest, grpc.ServerStreamingServer[OrderUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeOrders not implemented")
+}
+func (UnimplementedStatsServiceServer) mustEmbedUnimplementedStatsServiceServer() {}
+func (UnimplementedStatsServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeStatsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StatsServiceServer will
+// result in compilation errors.
+type UnsafeStatsServiceServer interface {
+	mustEmbedUnimplementedStatsServiceServer()
+}
+
+func RegisterStatsServiceServer(s grpc.ServiceRegistrar, srv StatsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedStatsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StatsService_ServiceDesc, srv)
+}
+
+func _StatsService_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_GetOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetOrderBook(ctx, req.(*GetOrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_SaveOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveOrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).SaveOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_SaveOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).SaveOrderBook(ctx, req.(*SaveOrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_GetOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_GetOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetOrderHistory(ctx, req.(*GetOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_SaveOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).SaveOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_SaveOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).SaveOrder(ctx, req.(*SaveOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_SubscribeOrderBooks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeOrderBooksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).SubscribeOrderBooks(m, &grpc.GenericServerStream[SubscribeOrderBooksRequest, OrderBookUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeOrderBooksServer = grpc.ServerStreamingServer[OrderBookUpdate]
+
+func _StatsService_SubscribeOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).SubscribeOrders(m, &grpc.GenericServerStream[SubscribeOrdersRequest, OrderUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeOrdersServer = grpc.ServerStreamingServer[OrderUpdate]
+
+// StatsService_ServiceDesc is the grpc.ServiceDesc for StatsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StatsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stats.v1.StatsService",
+	HandlerType: (*StatsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetOrderBook",
+			Handler:    _StatsService_GetOrderBook_Handler,
+		},
+		{
+			MethodName: "SaveOrderBook",
+			Handler:    _StatsService_SaveOrderBook_Handler,
+		},
+		{
+			MethodName: "GetOrderHistory",
+			Handler:    _StatsService_GetOrderHistory_Handler,
+		},
+		{
+			MethodName: "SaveOrder",
+			Handler:    _StatsService_SaveOrder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeOrderBooks",
+			Handler:       _StatsService_SubscribeOrderBooks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeOrders",
+			Handler:       _StatsService_SubscribeOrders_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "stats/v1/stats.proto",
+}
@@ -0,0 +1,165 @@
+// Пакет health реализует проверки живости и готовности сервиса для оркестратора.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояния проверок
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Функция проверки. Возвращает необязательное описание состояния
+// (например, версию схемы) и ошибку, если проверка не пройдена.
+type CheckFunc func(ctx context.Context) (string, error)
+
+// Результат одной проверки
+type CheckResult struct {
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latency_ms" example:"0.42"`
+	Detail    string  `json:"detail,omitempty" example:"version 3"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Ответ эндпоинтов /healthz и /readyz
+type Report struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Структура, выполняющая проверки живости и готовности
+type Checker struct {
+	timeout   time.Duration
+	liveness  []check
+	readiness []check
+	draining  atomic.Bool
+}
+
+// Конструктор для создания проверок; timeout ограничивает время каждой проверки
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Метод для добавления проверки живости. Такие проверки не должны зависеть
+// от внешних систем, иначе их сбой приведёт к перезапуску сервиса.
+func (c *Checker) AddLiveness(name string, fn CheckFunc) {
+	c.liveness = append(c.liveness, check{name: name, fn: fn})
+}
+
+// Метод для добавления проверки готовности принимать запросы
+func (c *Checker) AddReadiness(name string, fn CheckFunc) {
+	c.readiness = append(c.readiness, check{name: name, fn: fn})
+}
+
+// Метод, переводящий сервис в состояние остановки: после вызова
+// проверка готовности не проходит, чтобы на сервис перестали направлять запросы
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Метод для выполнения проверок живости
+func (c *Checker) Liveness(ctx context.Context) Report {
+	return c.run(ctx, c.liveness)
+}
+
+// Метод для выполнения проверок готовности
+func (c *Checker) Readiness(ctx context.Context) Report {
+	report := c.run(ctx, c.readiness)
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// Метод параллельного выполнения проверок
+func (c *Checker) run(ctx context.Context, checks []check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, chk.fn)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	for i, chk := range checks {
+		report.Checks[chk.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, fn CheckFunc) CheckResult {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	detail, err := fn(ctx)
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// @Summary Проверка живости
+// @Description Сервис работает и не требует перезапуска
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz [get]
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Liveness(r.Context()))
+	}
+}
+
+// @Summary Проверка готовности
+// @Description Сервис готов принимать запросы: база данных доступна, схема актуальна,
+// @Description буфер записи не переполнен, сервис не останавливается
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Readiness(r.Context()))
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, handler http.HandlerFunc) (int, Report) {
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var report Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	return rr.Code, report
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.AddReadiness("database", func(ctx context.Context) (string, error) { return "", nil })
+	checker.AddReadiness("migrations", func(ctx context.Context) (string, error) { return "version 3", nil })
+
+	code, report := serve(t, checker.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, "version 3", report.Checks["migrations"].Detail)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+
+	checker.AddReadiness("write_buffer", func(ctx context.Context) (string, error) { return "9/10 pending", errors.New("above high-water mark") })
+	code, report = serve(t, checker.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, CheckResult{Status: StatusFail, LatencyMs: report.Checks["write_buffer"].LatencyMs, Detail: "9/10 pending", Error: "above high-water mark"}, report.Checks["write_buffer"])
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	checker.AddReadiness("database", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Readiness(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+	assert.GreaterOrEqual(t, report.Checks["database"].LatencyMs, 20.0)
+}
+
+func TestChecker_Drain(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.AddReadiness("database", func(ctx context.Context) (string, error) { return "", nil })
+	checker.Drain()
+
+	code, report := serve(t, checker.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDraining, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+
+	// Живость от остановки не зависит
+	code, report = serve(t, checker.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Empty(t, report.Checks)
+}
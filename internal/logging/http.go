@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Middleware, записывающее в журнал каждый запрос: маршрут, код ответа,
+// длительность, адрес клиента и владельца ключа API. Должно работать внутри requestid.Middleware,
+// чтобы запись получила идентификатор запроса. Функция route возвращает
+// шаблон маршрута; пустая строка — маршрут не найден.
+func Middleware(logger *slog.Logger, route func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := api.NewResponseRecorder(w)
+		ctx, keyName := api.WithKeyNameHolder(r.Context())
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.StatusCode()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", route(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", recorder.Bytes),
+			slog.String("client_ip", api.ClientIP(r)),
+			slog.String("user_agent", r.UserAgent()),
+		}
+		if name := keyName(); name != "" {
+			attrs = append(attrs, slog.String("api_key", name))
+		}
+		logger.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+	})
+}
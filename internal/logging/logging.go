@@ -0,0 +1,70 @@
+// Пакет logging настраивает структурированное логирование на основе log/slog:
+// уровень и формат вывода, идентификатор запроса в каждой записи, журнал
+// HTTP-запросов и логирование ошибок репозитория.
+package logging
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/requestid"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Имена атрибутов с идентификаторами запроса и трассы
+const (
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+// Функция создания логгера по настройкам. Записи, сделанные с контекстом
+// запроса, получают атрибут request_id, а внутри записываемой трассы — также
+// trace_id и span_id.
+func New(cfg config.LoggingConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("logging.level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("logging.format %q must be text or json", cfg.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Обработчик, добавляющий к записи идентификаторы запроса и трассы из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() && span.IsSampled() {
+		record.AddAttrs(
+			slog.String(TraceIDKey, span.TraceID().String()),
+			slog.String(SpanIDKey, span.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/api"
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/requestid"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Функция создания логгера в формате JSON; records возвращает разобранные записи
+func newJSONLogger(t *testing.T) (*slog.Logger, func() []map[string]interface{}) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "info", Format: "json"}, &buf)
+	require.NoError(t, err)
+	records := func() []map[string]interface{} {
+		var result []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			result = append(result, record)
+		}
+		return result
+	}
+	return logger, records
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "warn", Format: "text"}, &buf)
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.WarnContext(requestid.NewContext(context.Background(), "abc"), "shown", "key", "value")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "msg=shown key=value request_id=abc")
+
+	_, err = New(config.LoggingConfig{Level: "verbose", Format: "text"}, &buf)
+	assert.Error(t, err)
+	_, err = New(config.LoggingConfig{Level: "info", Format: "xml"}, &buf)
+	assert.Error(t, err)
+}
+
+func TestNew_TraceContext(t *testing.T) {
+	logger, records := newJSONLogger(t)
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	span := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), span), "traced")
+	logger.InfoContext(context.Background(), "untraced")
+
+	got := records()
+	require.Len(t, got, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got[0][TraceIDKey])
+	assert.Equal(t, "00f067aa0ba902b7", got[0][SpanIDKey])
+	assert.NotContains(t, got[1], TraceIDKey)
+}
+
+func TestMiddleware(t *testing.T) {
+	logger, records := newJSONLogger(t)
+	handler := requestid.Middleware(Middleware(logger, func(r *http.Request) string { return "/orderbook/get" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/orderbook/get?pair=BTC", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	logged := records()
+	require.Len(t, logged, 1)
+	record := logged[0]
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "HTTP request", record["msg"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/orderbook/get", record["route"])
+	assert.Equal(t, float64(http.StatusInternalServerError), record["status"])
+	assert.Equal(t, "10.0.0.1", record["client_ip"])
+	assert.Equal(t, "test-agent", record["user_agent"])
+	assert.Equal(t, "req-1", record[RequestIDKey])
+	assert.Contains(t, record, "latency_ms")
+	assert.NotContains(t, record, "api_key")
+}
+
+func TestMiddleware_APIKey(t *testing.T) {
+	logger, records := newJSONLogger(t)
+	// Журнал работает до проверки ключа и получает имя владельца после обработки запроса
+	handler := Middleware(logger, func(r *http.Request) string { return "/orderbook/get" },
+		api.Authenticate(api.APIKeys{"abc": "collector-1"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/orderbook/get", nil)
+	req.Header.Set(api.APIKeyHeader, "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	logged := records()
+	require.Len(t, logged, 1)
+	assert.Equal(t, "collector-1", logged[0]["api_key"])
+}
+
+// Репозиторий, все методы которого завершаются ошибкой
+type failingRepository struct {
+	repository.Repository
+	err error
+}
+
+func (r failingRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	return nil, r.err
+}
+
+func (r failingRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	return r.err
+}
+
+func TestRepository(t *testing.T) {
+	logger, records := newJSONLogger(t)
+	ctx := requestid.NewContext(context.Background(), "req-2")
+
+	repo := Repository(failingRepository{err: fmt.Errorf("%w: connection refused", repository.ErrUnavailable)}, logger)
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.ErrorIs(t, err, repository.ErrUnavailable)
+
+	quiet := Repository(failingRepository{err: repository.ErrNotFound}, logger)
+	_, err = quiet.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	cancelled := Repository(failingRepository{err: context.Canceled}, logger)
+	_, err = cancelled.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.ErrorIs(t, err, context.Canceled)
+
+	logged := records()
+	require.Len(t, logged, 1, "not found and cancellation are not storage errors")
+	assert.Equal(t, "ERROR", logged[0]["level"])
+	assert.Equal(t, "GetOrderBook", logged[0]["method"])
+	assert.Equal(t, "Binance", logged[0]["exchange"])
+	assert.Equal(t, "storage unavailable: connection refused", logged[0]["error"])
+	assert.Equal(t, "req-2", logged[0][RequestIDKey])
+}
+
+func TestRepository_WithTx(t *testing.T) {
+	logger, records := newJSONLogger(t)
+	ctx := requestid.NewContext(context.Background(), "req-3")
+	repo := Repository(repository.NewInMemoryRepository(), logger)
+
+	abort := errors.New("abort")
+	err := repo.WithTx(ctx, func(tx repository.Repository) error { return abort })
+	assert.Same(t, abort, err)
+	assert.Empty(t, records(), "errors returned by fn are not repository errors")
+
+	// Ошибки вызовов внутри транзакции проходят через тот же фильтр
+	err = repo.WithTx(ctx, func(tx repository.Repository) error {
+		_, err := tx.GetOrderBook(ctx, "Binance", "BTC/USDT")
+		return err
+	})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Empty(t, records())
+
+	failing := Repository(failingRepository{err: fmt.Errorf("%w: bad order", repository.ErrInvalid)}, logger)
+	err = failing.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, &models.HistoryOrder{ExchangeName: "Binance", Pair: "BTC/USDT"})
+	require.ErrorIs(t, err, repository.ErrInvalid)
+	logged := records()
+	require.Len(t, logged, 1)
+	assert.Equal(t, "WARN", logged[0]["level"], "invalid data is the client's fault")
+	assert.Equal(t, "John Doe", logged[0]["client"])
+}
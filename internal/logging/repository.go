@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Декоратор репозитория, записывающий в журнал ошибки хранилища
+// с идентификатором запроса из контекста
+type loggingRepository struct {
+	repo   repository.Repository
+	logger *slog.Logger
+}
+
+// Функция для создания декоратора репозитория с логированием ошибок
+func Repository(repo repository.Repository, logger *slog.Logger) repository.Repository {
+	return &loggingRepository{repo: repo, logger: logger}
+}
+
+// Метод записи ошибки. Отсутствие данных и отмена запроса ошибками
+// хранилища не считаются и в журнал не попадают.
+func (r *loggingRepository) log(ctx context.Context, method string, err error, attrs ...slog.Attr) {
+	if err == nil || errors.Is(err, repository.ErrNotFound) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	level := slog.LevelError
+	if errors.Is(err, repository.ErrInvalid) || errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrVersionMismatch) {
+		level = slog.LevelWarn
+	}
+	attrs = append([]slog.Attr{slog.String("method", method), slog.String("error", err.Error())}, attrs...)
+	r.logger.LogAttrs(ctx, level, "Repository call failed", attrs...)
+}
+
+func (r *loggingRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	orderBook, err := r.repo.GetOrderBook(ctx, exchangeName, pair)
+	r.log(ctx, "GetOrderBook", err, slog.String("exchange", exchangeName), slog.String("pair", pair))
+	return orderBook, err
+}
+
+func (r *loggingRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	err := r.repo.SaveOrderBook(ctx, exchangeName, pair, orderBook)
+	r.log(ctx, "SaveOrderBook", err, slog.String("exchange", exchangeName), slog.String("pair", pair))
+	return err
+}
+
+func (r *loggingRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	snapshot, err := r.repo.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	r.log(ctx, "GetOrderBookSnapshot", err, slog.String("exchange", exchangeName), slog.String("pair", pair))
+	return snapshot, err
+}
+
+func (r *loggingRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	snapshot, err := r.repo.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion)
+	r.log(ctx, "SaveOrderBookSnapshot", err, slog.String("exchange", exchangeName), slog.String("pair", pair), slog.Int64("expected_version", expectedVersion))
+	return snapshot, err
+}
+
+func (r *loggingRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	history, err := r.repo.GetOrderHistory(ctx, client)
+	r.log(ctx, "GetOrderHistory", err, slog.String("client", client.ClientName))
+	return history, err
+}
+
+func (r *loggingRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	err := r.repo.SaveOrder(ctx, client, order)
+	r.log(ctx, "SaveOrder", err, slog.String("client", client.ClientName), slog.String("exchange", order.ExchangeName), slog.String("pair", order.Pair))
+	return err
+}
+
+// Метод выполнения транзакции. Ошибка fn возвращается без изменений и в журнал
+// не записывается: её причина уже записана вызовом внутри транзакции.
+func (r *loggingRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	var fnErr error
+	err := r.repo.WithTx(ctx, func(tx repository.Repository) error {
+		fnErr = fn(&loggingRepository{repo: tx, logger: r.logger})
+		return fnErr
+	})
+	if err != fnErr {
+		r.log(ctx, "WithTx", err)
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Метод для учёта завершённого вызова gRPC
+func (m *Metrics) observeGRPC(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	m.grpcRequests.WithLabelValues(method, code).Inc()
+	m.grpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+// Перехватчик, учитывающий число и длительность унарных вызовов gRPC
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// Перехватчик, учитывающий число и длительность потоковых вызовов gRPC.
+// Длительность подписки — время, в течение которого клиент был подключён.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		m.observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
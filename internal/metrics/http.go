@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Значение метки route для запросов, не совпавших ни с одним маршрутом
+const unmatchedRoute = "unmatched"
+
+// Middleware, учитывающее число и длительность запросов. Функция route
+// возвращает шаблон маршрута запроса, чтобы число значений метки не зависело
+// от путей, которые присылают клиенты; пустая строка — маршрут не найден.
+func (m *Metrics) Middleware(route func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := api.NewResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		name := route(r)
+		if name == "" {
+			name = unmatchedRoute
+		}
+		status := strconv.Itoa(recorder.StatusCode())
+		m.requests.WithLabelValues(name, r.Method, status).Inc()
+		m.requestDuration.WithLabelValues(name, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
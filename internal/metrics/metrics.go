@@ -0,0 +1,245 @@
+// Пакет metrics собирает метрики сервиса в формате Prometheus: HTTP-запросы,
+// вызовы gRPC, обращения к репозиторию, пул подключений к базе и кэш книг ордеров.
+package metrics
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/ratelimit"
+	"StatisticsCollectionService/internal/repository"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Префикс имён метрик сервиса
+const namespace = "stats"
+
+// Структура с метриками сервиса. Каждый экземпляр использует собственный
+// реестр, поэтому в одном процессе можно запустить несколько сервисов.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	repoDuration    *prometheus.HistogramVec
+	ordersIngested  *prometheus.CounterVec
+	rateLimited     *prometheus.CounterVec
+	grpcRequests    *prometheus.CounterVec
+	grpcDuration    *prometheus.HistogramVec
+
+	// Допустимые значения меток бирж и пар; nil — любые
+	exchanges map[string]bool
+	pairs     map[string]bool
+	maxPairs  int
+
+	mu sync.Mutex
+	// Сочетания биржи и пары, получившие собственные метки
+	known map[pairKey]struct{}
+	// Время последнего сохранения книг ордеров для метрики их возраста
+	orderBooks map[pairKey]time.Time
+	now        func() time.Time
+}
+
+// Значение меток биржи и пары, не попавших в допустимые
+const otherLabel = "other"
+
+// Ключ метрик по бирже и паре валют
+type pairKey struct {
+	exchange string
+	pair     string
+}
+
+// Конструктор для создания метрик с метриками среды выполнения Go и процесса.
+// cfg ограничивает значения меток бирж и пар валют.
+func New(cfg config.MetricsConfig) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Repository call latency by method and result.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"method", "result"}),
+		ordersIngested: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_ingested_total",
+			Help:      "Number of saved orders by exchange and pair.",
+		}, []string{"exchange", "pair"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Number of requests rejected by the rate limiter by route and limit class.",
+		}, []string{"route", "class"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Number of gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC call latency by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		exchanges:  labelSet(cfg.Exchanges),
+		pairs:      labelSet(cfg.Pairs),
+		maxPairs:   cfg.MaxPairs,
+		known:      make(map[pairKey]struct{}),
+		orderBooks: make(map[pairKey]time.Time),
+		now:        time.Now,
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.repoDuration,
+		m.ordersIngested,
+		m.rateLimited,
+		m.grpcRequests,
+		m.grpcDuration,
+		orderBookAgeCollector{m},
+	)
+	return m
+}
+
+// Метод для получения обработчика эндпоинта /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Метод для добавления статистики пула подключений к базе данных
+func (m *Metrics) RegisterDB(conn *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(conn, name))
+}
+
+// Метод для добавления статистики кэша книг ордеров
+func (m *Metrics) RegisterCache(cache *repository.CachingRepository) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of order book reads served from the cache.",
+		}, func() float64 { return float64(cache.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of order book reads that missed the cache.",
+		}, func() float64 { return float64(cache.Stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_evictions_total",
+			Help:      "Number of order books evicted from the cache.",
+		}, func() float64 { return float64(cache.Stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Number of order books in the cache.",
+		}, func() float64 { return float64(cache.Stats().Size) }),
+	)
+}
+
+// Метод для учёта запроса, отклонённого ограничителем частоты
+func (m *Metrics) RateLimited(route, class string) {
+	m.rateLimited.WithLabelValues(route, class).Inc()
+}
+
+// Метод для добавления числа клиентов, отслеживаемых ограничителем частоты
+func (m *Metrics) RegisterRateLimiter(limiter *ratelimit.Limiter, class string) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "rate_limiter_keys",
+		Help:        "Number of clients tracked by the rate limiter.",
+		ConstLabels: prometheus.Labels{"class": class},
+	}, func() float64 { return float64(limiter.Len()) }))
+}
+
+// Функция построения множества допустимых значений метки; nil — любые
+func labelSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// Метод выбора меток биржи и пары валют; вызывается с захваченной блокировкой.
+// Значения вне допустимых и новые сочетания сверх maxPairs заменяются на
+// "other", чтобы клиенты не могли создать неограниченное число рядов.
+func (m *Metrics) pairLabels(exchange, pair string) pairKey {
+	key := pairKey{exchange: exchange, pair: pair}
+	if m.exchanges != nil && !m.exchanges[exchange] {
+		key.exchange = otherLabel
+	}
+	if m.pairs != nil && !m.pairs[pair] {
+		key.pair = otherLabel
+	}
+	if _, ok := m.known[key]; ok {
+		return key
+	}
+	if len(m.known) >= m.maxPairs {
+		return pairKey{exchange: otherLabel, pair: otherLabel}
+	}
+	m.known[key] = struct{}{}
+	return key
+}
+
+// Метод для учёта сохранённого ордера
+func (m *Metrics) orderSaved(exchange, pair string) {
+	m.mu.Lock()
+	key := m.pairLabels(exchange, pair)
+	m.mu.Unlock()
+	m.ordersIngested.WithLabelValues(key.exchange, key.pair).Inc()
+}
+
+// Метод для запоминания времени сохранения книги ордеров. Число книг
+// ограничено так же, как число сочетаний меток.
+func (m *Metrics) orderBookSaved(exchange, pair string) {
+	m.mu.Lock()
+	m.orderBooks[m.pairLabels(exchange, pair)] = m.now()
+	m.mu.Unlock()
+}
+
+// Коллектор возраста книг ордеров: время с последнего сохранения
+// каждой книги этим экземпляром сервиса, вычисляемое в момент сбора
+type orderBookAgeCollector struct {
+	m *Metrics
+}
+
+var orderBookAgeDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "order_book_age_seconds"),
+	"Time since the order book was last saved by this instance.",
+	[]string{"exchange", "pair"}, nil,
+)
+
+func (c orderBookAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- orderBookAgeDesc
+}
+
+func (c orderBookAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	now := c.m.now()
+	for key, saved := range c.m.orderBooks {
+		ch <- prometheus.MustNewConstMetric(orderBookAgeDesc, prometheus.GaugeValue, now.Sub(saved).Seconds(), key.exchange, key.pair)
+	}
+}
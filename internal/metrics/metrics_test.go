@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New(config.Default().Metrics)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orderbook/get", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/order/save", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := m.Middleware(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}, mux)
+
+	for _, target := range []string{"/orderbook/get?pair=BTC", "/orderbook/get?pair=ETH", "/order/save", "/random/1", "/random/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("/orderbook/get", "GET", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("/order/save", "GET", "200")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(unmatchedRoute, "GET", "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestRepository(t *testing.T) {
+	m := New(config.Default().Metrics)
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	repo := m.Repository(repository.NewInMemoryRepository())
+	ctx := context.Background()
+	order := func(label string) *models.HistoryOrder {
+		return &models.HistoryOrder{ClientName: "John Doe", ExchangeName: "Binance", Pair: "BTC/USDT", Label: label}
+	}
+
+	require.NoError(t, repo.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, order("first")))
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}))
+	_, err := repo.GetOrderBook(ctx, "Binance", "ETH/USDT")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	// Откаченная транзакция в метриках не учитывается
+	err = repo.WithTx(ctx, func(tx repository.Repository) error {
+		require.NoError(t, tx.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, order("rolled back")))
+		return errors.New("abort")
+	})
+	require.Error(t, err)
+	require.NoError(t, repo.WithTx(ctx, func(tx repository.Repository) error {
+		return tx.WithTx(ctx, func(nested repository.Repository) error {
+			return nested.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, order("nested"))
+		})
+	}))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.ordersIngested.WithLabelValues("Binance", "BTC/USDT")))
+	count := func(method, result string) uint64 {
+		var metric dto.Metric
+		require.NoError(t, m.repoDuration.WithLabelValues(method, result).(prometheus.Metric).Write(&metric))
+		return metric.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(1), count("GetOrderBook", "not_found"))
+	assert.Equal(t, uint64(1), count("WithTx", "error"))
+	assert.Equal(t, uint64(1), count("WithTx", "ok"))
+
+	now = now.Add(90 * time.Second)
+	expected := `
+# HELP stats_order_book_age_seconds Time since the order book was last saved by this instance.
+# TYPE stats_order_book_age_seconds gauge
+stats_order_book_age_seconds{exchange="Binance",pair="BTC/USDT"} 90
+`
+	assert.NoError(t, testutil.CollectAndCompare(orderBookAgeCollector{m}, strings.NewReader(expected)))
+}
+
+func TestRepository_LabelLimits(t *testing.T) {
+	m := New(config.MetricsConfig{Exchanges: []string{"Binance", "Kraken"}, MaxPairs: 2})
+	repo := m.Repository(repository.NewInMemoryRepository())
+	ctx := context.Background()
+	save := func(exchange, pair string) {
+		order := &models.HistoryOrder{ExchangeName: exchange, Pair: pair}
+		require.NoError(t, repo.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, order))
+		require.NoError(t, repo.SaveOrderBook(ctx, exchange, pair, nil))
+	}
+
+	save("Binance", "BTC/USDT")
+	save("Unknown", "BTC/USDT")
+	// Сочетаний больше max_pairs: новые учитываются как other/other
+	for i := 0; i < 10; i++ {
+		save("Kraken", fmt.Sprintf("PAIR%d/USDT", i))
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.ordersIngested.WithLabelValues("Binance", "BTC/USDT")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.ordersIngested.WithLabelValues("other", "BTC/USDT")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.ordersIngested.WithLabelValues("other", "other")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.ordersIngested))
+	assert.Equal(t, 3, testutil.CollectAndCount(orderBookAgeCollector{m}))
+}
+
+func TestRegisterCacheAndDB(t *testing.T) {
+	m := New(config.Default().Metrics)
+	cache := repository.NewCachingRepository(repository.NewInMemoryRepository(), 10, time.Minute)
+	m.RegisterCache(cache)
+	ctx := context.Background()
+	// Сохранение удаляет книгу из кэша, первое чтение — промах
+	require.NoError(t, cache.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}))
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetOrderBook(ctx, "Binance", "BTC/USDT")
+		require.NoError(t, err)
+	}
+	_, err := cache.GetOrderBook(ctx, "Binance", "ETH/USDT")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	conn, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer conn.Close()
+	m.RegisterDB(conn, "primary")
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	assert.Contains(t, body, "stats_cache_hits_total 2")
+	assert.Contains(t, body, "stats_cache_misses_total 2")
+	assert.Contains(t, body, "stats_cache_entries 1")
+	assert.Contains(t, body, `go_sql_max_open_connections{db_name="primary"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
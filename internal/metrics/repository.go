@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+// Декоратор репозитория, измеряющий длительность вызовов и учитывающий
+// сохранённые ордера и книги ордеров
+type instrumentedRepository struct {
+	repo repository.Repository
+	m    *Metrics
+}
+
+// Метод для создания декоратора репозитория с метриками
+func (m *Metrics) Repository(repo repository.Repository) repository.Repository {
+	return &instrumentedRepository{repo: repo, m: m}
+}
+
+// Функция определения значения метки result по ошибке репозитория
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, repository.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return "version_mismatch"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+func (r *instrumentedRepository) observe(method string, start time.Time, err error) {
+	r.m.repoDuration.WithLabelValues(method, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func (r *instrumentedRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	start := time.Now()
+	orderBook, err := r.repo.GetOrderBook(ctx, exchangeName, pair)
+	r.observe("GetOrderBook", start, err)
+	return orderBook, err
+}
+
+func (r *instrumentedRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	start := time.Now()
+	err := r.repo.SaveOrderBook(ctx, exchangeName, pair, orderBook)
+	r.observe("SaveOrderBook", start, err)
+	if err == nil {
+		r.m.orderBookSaved(exchangeName, pair)
+	}
+	return err
+}
+
+func (r *instrumentedRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	start := time.Now()
+	snapshot, err := r.repo.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	r.observe("GetOrderBookSnapshot", start, err)
+	return snapshot, err
+}
+
+func (r *instrumentedRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	start := time.Now()
+	snapshot, err := r.repo.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion)
+	r.observe("SaveOrderBookSnapshot", start, err)
+	if err == nil {
+		r.m.orderBookSaved(exchangeName, pair)
+	}
+	return snapshot, err
+}
+
+func (r *instrumentedRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	start := time.Now()
+	history, err := r.repo.GetOrderHistory(ctx, client)
+	r.observe("GetOrderHistory", start, err)
+	return history, err
+}
+
+func (r *instrumentedRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	start := time.Now()
+	err := r.repo.SaveOrder(ctx, client, order)
+	r.observe("SaveOrder", start, err)
+	if err == nil {
+		r.m.orderSaved(order.ExchangeName, order.Pair)
+	}
+	return err
+}
+
+// Метод выполнения транзакции. Сохранения внутри транзакции учитываются
+// в метриках ордеров и книг только после её фиксации.
+func (r *instrumentedRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	start := time.Now()
+	var tx *recordingTx
+	err := r.repo.WithTx(ctx, func(inner repository.Repository) error {
+		tx = &recordingTx{Repository: inner}
+		return fn(tx)
+	})
+	r.observe("WithTx", start, err)
+	if err == nil && tx != nil {
+		for _, order := range tx.orders {
+			r.m.orderSaved(order.exchange, order.pair)
+		}
+		for _, book := range tx.orderBooks {
+			r.m.orderBookSaved(book.exchange, book.pair)
+		}
+	}
+	return err
+}
+
+// Репозиторий транзакции, запоминающий сохранённые ордера и книги
+type recordingTx struct {
+	repository.Repository
+	orders     []pairKey
+	orderBooks []pairKey
+}
+
+func (t *recordingTx) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	err := t.Repository.SaveOrderBook(ctx, exchangeName, pair, orderBook)
+	if err == nil {
+		t.orderBooks = append(t.orderBooks, pairKey{exchange: exchangeName, pair: pair})
+	}
+	return err
+}
+
+func (t *recordingTx) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	snapshot, err := t.Repository.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion)
+	if err == nil {
+		t.orderBooks = append(t.orderBooks, pairKey{exchange: exchangeName, pair: pair})
+	}
+	return snapshot, err
+}
+
+func (t *recordingTx) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	err := t.Repository.SaveOrder(ctx, client, order)
+	if err == nil {
+		t.orders = append(t.orders, pairKey{exchange: order.ExchangeName, pair: order.Pair})
+	}
+	return err
+}
+
+// Вложенная транзакция присоединяется к текущей, поэтому сохранения в ней
+// учитываются вместе с внешней
+func (t *recordingTx) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return t.Repository.WithTx(ctx, func(repository.Repository) error { return fn(t) })
+}
@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestLoad_Embedded(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, SQLite} {
+		migrations, err := Load(migrationsFS, dialect.dir)
+		assert.NoError(t, err, dialect.Name)
+		assert.NotEmpty(t, migrations, dialect.Name)
+
+		for i, m := range migrations {
+			assert.Equal(t, i+1, m.Version)
+			assert.NotEmpty(t, m.Up)
+			assert.NotEmpty(t, m.Down)
+		}
+	}
+}
+
+func TestLoad_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"sql/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+
+	migrations, err := Load(fsys, "sql")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "second", migrations[1].Name)
+	assert.Equal(t, "DROP TABLE b;", migrations[1].Down)
+}
+
+func TestLoad_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+	}
+
+	_, err := Load(fsys, "sql")
+	assert.Error(t, err)
+}
+
+func TestLoad_InvalidName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/first.sql": {Data: []byte("CREATE TABLE a ();")},
+	}
+
+	_, err := Load(fsys, "sql")
+	assert.Error(t, err)
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrations.db"))
+	if err != nil {
+		t.Fatalf("Error opening test database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewMigrator(db, SQLite)
+	assert.NoError(t, err)
+
+	err = migrator.Check(ctx)
+	assert.ErrorIs(t, err, ErrSchemaOutdated)
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, migrator.Latest())
+	assert.NoError(t, migrator.Check(ctx))
+
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+		assert.False(t, s.AppliedAt.IsZero())
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+
+	version, err := migrator.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.Latest()-1, version)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_history;
+DROP TABLE IF EXISTS order_books;
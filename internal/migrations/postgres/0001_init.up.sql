@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS order_books (
+    id SERIAL PRIMARY KEY,
+    exchange VARCHAR(255) NOT NULL,
+    pair VARCHAR(255) NOT NULL,
+    asks JSONB NOT NULL,
+    bids JSONB NOT NULL,
+    UNIQUE (exchange, pair)
+);
+
+CREATE TABLE IF NOT EXISTS order_history (
+    id SERIAL PRIMARY KEY,
+    client_name VARCHAR(255) NOT NULL,
+    exchange_name VARCHAR(255) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    pair VARCHAR(255) NOT NULL,
+    side VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    base_qty DOUBLE PRECISION NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    algorithm_name_placed VARCHAR(255) NOT NULL,
+    lowest_sell_prc DOUBLE PRECISION NOT NULL,
+    highest_buy_prc DOUBLE PRECISION NOT NULL,
+    commission_quote_qty DOUBLE PRECISION NOT NULL,
+    time_placed TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_history_client_name_idx ON order_history (client_name);
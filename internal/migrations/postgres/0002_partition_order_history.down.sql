@@ -0,0 +1,27 @@
+-- Возврат order_history к обычной таблице. Отсоединённые (архивные) секции не затрагиваются.
+ALTER TABLE order_history RENAME TO order_history_partitioned;
+ALTER INDEX order_history_client_name_idx RENAME TO order_history_partitioned_client_name_idx;
+
+CREATE TABLE order_history (
+    id INTEGER NOT NULL DEFAULT nextval('order_history_id_seq') PRIMARY KEY,
+    client_name VARCHAR(255) NOT NULL,
+    exchange_name VARCHAR(255) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    pair VARCHAR(255) NOT NULL,
+    side VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    base_qty DOUBLE PRECISION NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    algorithm_name_placed VARCHAR(255) NOT NULL,
+    lowest_sell_prc DOUBLE PRECISION NOT NULL,
+    highest_buy_prc DOUBLE PRECISION NOT NULL,
+    commission_quote_qty DOUBLE PRECISION NOT NULL,
+    time_placed TIMESTAMP NOT NULL
+);
+
+ALTER SEQUENCE order_history_id_seq OWNED BY order_history.id;
+
+CREATE INDEX order_history_client_name_idx ON order_history (client_name);
+
+INSERT INTO order_history SELECT * FROM order_history_partitioned ORDER BY id;
+DROP TABLE order_history_partitioned;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS order_books_updated_at_idx;
+ALTER TABLE order_books DROP COLUMN updated_at;
@@ -0,0 +1,4 @@
+-- Время последнего сохранения книги ордеров, по которому применяется срок хранения
+ALTER TABLE order_books ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC');
+
+CREATE INDEX order_books_updated_at_idx ON order_books (updated_at);
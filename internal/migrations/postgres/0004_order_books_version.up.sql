@@ -0,0 +1,2 @@
+-- Номер сохранения книги ордеров: основа ETag и условной записи (If-Match)
+ALTER TABLE order_books ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS order_books (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exchange TEXT NOT NULL,
+    pair TEXT NOT NULL,
+    asks TEXT NOT NULL,
+    bids TEXT NOT NULL,
+    UNIQUE (exchange, pair)
+);
+
+CREATE TABLE IF NOT EXISTS order_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_name TEXT NOT NULL,
+    exchange_name TEXT NOT NULL,
+    label TEXT NOT NULL,
+    pair TEXT NOT NULL,
+    side TEXT NOT NULL,
+    type TEXT NOT NULL,
+    base_qty REAL NOT NULL,
+    price REAL NOT NULL,
+    algorithm_name_placed TEXT NOT NULL,
+    lowest_sell_prc REAL NOT NULL,
+    highest_buy_prc REAL NOT NULL,
+    commission_quote_qty REAL NOT NULL,
+    time_placed TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_history_client_name_idx ON order_history (client_name);
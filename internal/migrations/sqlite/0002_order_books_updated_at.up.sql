@@ -0,0 +1,7 @@
+-- Время последнего сохранения книги ордеров, по которому применяется срок хранения.
+-- SQLite не допускает непостоянное значение по умолчанию при добавлении столбца,
+-- поэтому существующие строки заполняются отдельно.
+ALTER TABLE order_books ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE order_books SET updated_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX order_books_updated_at_idx ON order_books (updated_at);
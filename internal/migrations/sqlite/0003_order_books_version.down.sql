@@ -0,0 +1 @@
+ALTER TABLE order_books DROP COLUMN version;
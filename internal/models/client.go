@@ -1,8 +1,13 @@
 package models
 
+import "time"
+
 type Client struct {
 	ClientName   string `json:"client_name"`
 	ExchangeName string `json:"exchange_name"`
 	Label        string `json:"label"`
 	Pair         string `json:"pair"`
+	// Необязательные границы периода истории ордеров: [TimeFrom, TimeTo)
+	TimeFrom *time.Time `json:"time_from,omitempty"`
+	TimeTo   *time.Time `json:"time_to,omitempty"`
 }
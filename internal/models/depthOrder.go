@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type DepthOrder struct {
 	Price   float64 `json:"price"`
 	BaseQty float64 `json:"base_qty"`
@@ -12,3 +14,11 @@ type OrderBook struct {
 	Asks     []DepthOrder `json:"asks"`
 	Bids     []DepthOrder `json:"bids"`
 }
+
+// Сохранённая книга ордеров с номером версии и временем сохранения
+type OrderBookSnapshot struct {
+	OrderBook []*DepthOrder
+	// Номер сохранения книги: 1 при первом сохранении, далее увеличивается на 1
+	Version   int64
+	UpdatedAt time.Time
+}
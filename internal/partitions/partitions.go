@@ -0,0 +1,336 @@
+// Пакет partitions управляет месячными секциями таблицы order_history в PostgreSQL:
+// создаёт секции заранее, переносит в них строки из секции по умолчанию
+// и отсоединяет или удаляет секции старше срока хранения.
+package partitions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Действия с секциями старше срока хранения
+const (
+	// Секция отсоединяется и удаляется вместе с данными
+	ActionDrop = "drop"
+	// Секция отсоединяется и остаётся в базе отдельной таблицей
+	ActionDetach = "detach"
+)
+
+const (
+	parentTable      = "order_history"
+	defaultPartition = "order_history_default"
+	boundLayout      = "2006-01-02 15:04:05"
+)
+
+// Параметры управления секциями
+type Options struct {
+	// Число месяцев вперёд, для которых секции создаются заранее
+	Premake int
+	// Срок хранения в месяцах; 0 — хранить все секции
+	Retention int
+	// Действие с секциями старше срока хранения: ActionDrop или ActionDetach
+	RetentionAction string
+}
+
+// Секция таблицы order_history
+type Partition struct {
+	Name string
+	// Границы диапазона time_placed [From, To); нулевые для секции по умолчанию
+	From time.Time
+	To   time.Time
+	// Оценка числа строк по статистике PostgreSQL
+	Rows    int64
+	Default bool
+}
+
+// Результат обслуживания секций
+type Report struct {
+	Created []Partition
+	Expired []Partition
+}
+
+// Структура для управления секциями order_history
+type Manager struct {
+	db   *sql.DB
+	opts Options
+	now  func() time.Time
+}
+
+// Конструктор для создания менеджера секций
+func NewManager(db *sql.DB, opts Options) *Manager {
+	if opts.RetentionAction == "" {
+		opts.RetentionAction = ActionDrop
+	}
+	return &Manager{db: db, opts: opts, now: time.Now}
+}
+
+var boundPattern = regexp.MustCompile(`FROM \('([^']+)'\) TO \('([^']+)'\)`)
+
+// Метод для получения списка секций, отсортированного по началу диапазона
+func (m *Manager) Status(ctx context.Context) ([]Partition, error) {
+	query := `SELECT c.relname, pg_get_expr(c.relpartbound, c.oid), c.reltuples::bigint
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass`
+	rows, err := m.db.QueryContext(ctx, query, parentTable)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []Partition
+	for rows.Next() {
+		var p Partition
+		var bound string
+		if err := rows.Scan(&p.Name, &bound, &p.Rows); err != nil {
+			return nil, fmt.Errorf("list partitions: %w", err)
+		}
+		if p.Rows < 0 {
+			// Таблица ещё не анализировалась
+			p.Rows = 0
+		}
+		if bound == "DEFAULT" {
+			p.Default = true
+		} else if p.From, p.To, err = parseBound(bound); err != nil {
+			return nil, fmt.Errorf("partition %s: %w", p.Name, err)
+		}
+		partitions = append(partitions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Default != partitions[j].Default {
+			return partitions[j].Default
+		}
+		return partitions[i].From.Before(partitions[j].From)
+	})
+	return partitions, nil
+}
+
+// Функция разбора границ секции из вывода pg_get_expr
+func parseBound(bound string) (from, to time.Time, err error) {
+	match := boundPattern.FindStringSubmatch(bound)
+	if match == nil {
+		return from, to, fmt.Errorf("unsupported partition bound %q", bound)
+	}
+	if from, err = time.Parse(boundLayout, match[1]); err != nil {
+		return from, to, err
+	}
+	to, err = time.Parse(boundLayout, match[2])
+	return from, to, err
+}
+
+// Метод для создания недостающих секций: от текущего месяца на Premake месяцев
+// вперёд, а также для месяцев, строки которых лежат в секции по умолчанию
+func (m *Manager) Ensure(ctx context.Context) ([]Partition, error) {
+	existing, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stray, err := m.defaultMonths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []Partition
+	for _, p := range missingPartitions(existing, stray, m.now(), m.opts.Premake) {
+		if err := m.create(ctx, p); err != nil {
+			return created, err
+		}
+		created = append(created, p)
+	}
+	return created, nil
+}
+
+// Метод для отсоединения (и удаления при ActionDrop) секций старше срока хранения
+func (m *Manager) Expire(ctx context.Context) ([]Partition, error) {
+	existing, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []Partition
+	for _, p := range expiredPartitions(existing, m.now(), m.opts.Retention) {
+		if err := m.expire(ctx, p); err != nil {
+			return expired, err
+		}
+		expired = append(expired, p)
+	}
+	return expired, nil
+}
+
+// Метод для полного обслуживания секций: создание и истечение срока хранения
+func (m *Manager) Maintain(ctx context.Context) (Report, error) {
+	var report Report
+	var err error
+	if report.Created, err = m.Ensure(ctx); err != nil {
+		return report, err
+	}
+	report.Expired, err = m.Expire(ctx)
+	return report, err
+}
+
+// Метод для периодического обслуживания секций до отмены контекста
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := m.Maintain(ctx)
+		for _, p := range report.Created {
+			slog.Info("Created partition", "partition", p.Name)
+		}
+		for _, p := range report.Expired {
+			slog.Info("Expired partition", "partition", p.Name, "action", m.opts.RetentionAction)
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Error maintaining partitions", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Метод получения месяцев, строки которых находятся в секции по умолчанию
+func (m *Manager) defaultMonths(ctx context.Context) ([]time.Time, error) {
+	query := `SELECT DISTINCT date_trunc('month', time_placed) FROM ` + defaultPartition
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("scan default partition: %w", err)
+	}
+	defer rows.Close()
+
+	var months []time.Time
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, fmt.Errorf("scan default partition: %w", err)
+		}
+		months = append(months, month.UTC())
+	}
+	return months, rows.Err()
+}
+
+// Метод создания секции. Строки её диапазона переносятся из секции по умолчанию
+// в той же транзакции, иначе PostgreSQL не позволит присоединить секцию.
+func (m *Manager) create(ctx context.Context, p Partition) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create partition %s: %w", p.Name, err)
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{query: fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, p.Name, parentTable)},
+		{
+			query: fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE time_placed >= $1 AND time_placed < $2 RETURNING *) INSERT INTO %s SELECT * FROM moved`, defaultPartition, p.Name),
+			args:  []interface{}{p.From, p.To},
+		},
+		{query: fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+			parentTable, p.Name, p.From.Format(boundLayout), p.To.Format(boundLayout))},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("create partition %s: %w", p.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create partition %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Метод отсоединения секции и, при ActionDrop, её удаления
+func (m *Manager) expire(ctx context.Context, p Partition) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("expire partition %s: %w", p.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, parentTable, p.Name)); err != nil {
+		return fmt.Errorf("expire partition %s: %w", p.Name, err)
+	}
+	if m.opts.RetentionAction == ActionDrop {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, p.Name)); err != nil {
+			return fmt.Errorf("expire partition %s: %w", p.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("expire partition %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Функция получения начала месяца в UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Функция описания месячной секции, начинающейся с month
+func monthPartition(month time.Time) Partition {
+	return Partition{
+		Name: fmt.Sprintf("%s_p%s", parentTable, month.Format("200601")),
+		From: month,
+		To:   month.AddDate(0, 1, 0),
+	}
+}
+
+// Функция определения секций, которые нужно создать
+func missingPartitions(existing []Partition, stray []time.Time, now time.Time, premake int) []Partition {
+	covered := func(month time.Time) bool {
+		for _, p := range existing {
+			if !p.Default && !month.Before(p.From) && month.Before(p.To) {
+				return true
+			}
+		}
+		return false
+	}
+
+	wanted := map[time.Time]bool{}
+	current := monthStart(now)
+	for i := 0; i <= premake; i++ {
+		wanted[current.AddDate(0, i, 0)] = true
+	}
+	for _, month := range stray {
+		wanted[monthStart(month)] = true
+	}
+
+	var missing []Partition
+	for month := range wanted {
+		if !covered(month) {
+			missing = append(missing, monthPartition(month))
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].From.Before(missing[j].From) })
+	return missing
+}
+
+// Функция определения секций, целиком вышедших за срок хранения
+func expiredPartitions(existing []Partition, now time.Time, retention int) []Partition {
+	if retention <= 0 {
+		return nil
+	}
+	cutoff := monthStart(now).AddDate(0, -retention, 0)
+
+	var expired []Partition
+	for _, p := range existing {
+		if !p.Default && !p.To.After(cutoff) {
+			expired = append(expired, p)
+		}
+	}
+	return expired
+}
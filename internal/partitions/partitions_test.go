@@ -0,0 +1,64 @@
+package partitions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseBound(t *testing.T) {
+	from, to, err := parseBound("FOR VALUES FROM ('2024-03-01 00:00:00') TO ('2024-04-01 00:00:00')")
+	assert.NoError(t, err)
+	assert.Equal(t, month(2024, time.March), from)
+	assert.Equal(t, month(2024, time.April), to)
+
+	_, _, err = parseBound("FOR VALUES IN (1)")
+	assert.Error(t, err)
+}
+
+func TestMonthPartition(t *testing.T) {
+	p := monthPartition(month(2024, time.December))
+	assert.Equal(t, "order_history_p202412", p.Name)
+	assert.Equal(t, month(2025, time.January), p.To)
+}
+
+func TestMissingPartitions(t *testing.T) {
+	existing := []Partition{
+		{Name: "order_history_default", Default: true},
+		monthPartition(month(2024, time.March)),
+	}
+	now := time.Date(2024, time.March, 20, 23, 0, 0, 0, time.FixedZone("", -5*60*60))
+	stray := []time.Time{month(2023, time.November), month(2024, time.March)}
+
+	missing := missingPartitions(existing, stray, now, 2)
+	var names []string
+	for _, p := range missing {
+		names = append(names, p.Name)
+	}
+	// 20 марта 23:00 по UTC-5 — это уже 21 марта по UTC, текущий месяц — март
+	assert.Equal(t, []string{"order_history_p202311", "order_history_p202404", "order_history_p202405"}, names)
+}
+
+func TestMissingPartitions_NextMonthAfterTimeZoneShift(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 22, 0, 0, 0, time.FixedZone("", -5*60*60))
+	missing := missingPartitions(nil, nil, now, 0)
+	assert.Equal(t, []Partition{monthPartition(month(2024, time.April))}, missing)
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	existing := []Partition{
+		monthPartition(month(2023, time.December)),
+		monthPartition(month(2024, time.January)),
+		monthPartition(month(2024, time.February)),
+		{Name: "order_history_default", Default: true},
+	}
+	now := time.Date(2024, time.April, 10, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, existing[:2], expiredPartitions(existing, now, 2))
+	assert.Empty(t, expiredPartitions(existing, now, 0), "zero retention keeps everything")
+}
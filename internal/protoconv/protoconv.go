@@ -0,0 +1,105 @@
+// Пакет protoconv преобразует модели в сообщения proto/stats/v1/stats.proto
+// и обратно. Используется gRPC API и HTTP API при ответах в формате Protobuf.
+package protoconv
+
+import (
+	"StatisticsCollectionService/internal/grpcapi/statsv1"
+	"StatisticsCollectionService/internal/models"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Функция преобразования ордеров книги в сообщения
+func DepthOrdersToProto(orders []*models.DepthOrder) []*statsv1.DepthOrder {
+	result := make([]*statsv1.DepthOrder, len(orders))
+	for i, order := range orders {
+		result[i] = &statsv1.DepthOrder{Price: order.Price, BaseQty: order.BaseQty}
+	}
+	return result
+}
+
+// Функция преобразования ордеров книги из сообщений
+func DepthOrdersFromProto(orders []*statsv1.DepthOrder) []*models.DepthOrder {
+	result := make([]*models.DepthOrder, len(orders))
+	for i, order := range orders {
+		result[i] = &models.DepthOrder{Price: order.GetPrice(), BaseQty: order.GetBaseQty()}
+	}
+	return result
+}
+
+// Функция преобразования ордера в сообщение
+func HistoryOrderToProto(order *models.HistoryOrder) *statsv1.HistoryOrder {
+	return &statsv1.HistoryOrder{
+		ClientName:          order.ClientName,
+		ExchangeName:        order.ExchangeName,
+		Label:               order.Label,
+		Pair:                order.Pair,
+		Side:                order.Side,
+		Type:                order.Type,
+		BaseQty:             order.BaseQty,
+		Price:               order.Price,
+		AlgorithmNamePlaced: order.AlgorithmNamePlaced,
+		LowestSellPrc:       order.LowestSellPrice,
+		HighestBuyPrc:       order.HighestBuyPrice,
+		CommissionQuoteQty:  order.CommissionQuoteQty,
+		TimePlaced:          timestamppb.New(order.TimePlaced),
+	}
+}
+
+// Функция преобразования ордера из сообщения; nil — ордер не передан
+func HistoryOrderFromProto(order *statsv1.HistoryOrder) *models.HistoryOrder {
+	if order == nil {
+		return nil
+	}
+	var placed time.Time
+	if order.GetTimePlaced() != nil {
+		placed = order.GetTimePlaced().AsTime()
+	}
+	return &models.HistoryOrder{
+		ClientName:          order.GetClientName(),
+		ExchangeName:        order.GetExchangeName(),
+		Label:               order.GetLabel(),
+		Pair:                order.GetPair(),
+		Side:                order.GetSide(),
+		Type:                order.GetType(),
+		BaseQty:             order.GetBaseQty(),
+		Price:               order.GetPrice(),
+		AlgorithmNamePlaced: order.GetAlgorithmNamePlaced(),
+		LowestSellPrice:     order.GetLowestSellPrc(),
+		HighestBuyPrice:     order.GetHighestBuyPrc(),
+		CommissionQuoteQty:  order.GetCommissionQuoteQty(),
+		TimePlaced:          placed,
+	}
+}
+
+// Функция преобразования истории ордеров в сообщения
+func HistoryToProto(history []*models.HistoryOrder) []*statsv1.HistoryOrder {
+	result := make([]*statsv1.HistoryOrder, len(history))
+	for i, order := range history {
+		result[i] = HistoryOrderToProto(order)
+	}
+	return result
+}
+
+// Функция преобразования клиента из сообщения; nil — клиент не передан
+func ClientFromProto(client *statsv1.Client) *models.Client {
+	if client == nil {
+		return nil
+	}
+	result := &models.Client{
+		ClientName:   client.GetClientName(),
+		ExchangeName: client.GetExchangeName(),
+		Label:        client.GetLabel(),
+		Pair:         client.GetPair(),
+	}
+	if client.GetTimeFrom() != nil {
+		from := client.GetTimeFrom().AsTime()
+		result.TimeFrom = &from
+	}
+	if client.GetTimeTo() != nil {
+		to := client.GetTimeTo().AsTime()
+		result.TimeTo = &to
+	}
+	return result
+}
@@ -0,0 +1,132 @@
+// Пакет ratelimit ограничивает частоту запросов алгоритмом token bucket:
+// у каждого ключа (клиента, ключа API, адреса) своя корзина, которая
+// пополняется с постоянной скоростью до заданного объёма.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ограничение: Rate — число запросов в секунду, Burst — объём корзины,
+// то есть сколько запросов можно выполнить подряд без ожидания
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Результат проверки запроса
+type Decision struct {
+	Allowed bool
+	// Объём корзины
+	Limit int
+	// Число запросов, которые можно выполнить сразу после этого
+	Remaining int
+	// Время до появления токена для следующего запроса; 0, если токен есть
+	RetryAfter time.Duration
+	// Время до полного пополнения корзины
+	Reset time.Duration
+}
+
+// Корзина одного ключа: число токенов на момент last
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Структура, ограничивающая частоту запросов по ключам. Корзины создаются
+// при первом запросе ключа; полные корзины удаляются методом Cleanup.
+type Limiter struct {
+	limit Limit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// Конструктор для создания ограничителя
+func New(limit Limit) *Limiter {
+	return &Limiter{limit: limit, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Метод для получения ограничения
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Метод проверки запроса ключа. Разрешённый запрос забирает токен из корзины.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.limit)
+
+	d := Decision{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.duration(float64(l.limit.Burst) - b.tokens)
+	return d
+}
+
+// Метод пополнения корзины за время с последнего обращения
+func (b *bucket) refill(now time.Time, limit Limit) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.last = now
+	}
+}
+
+// Метод расчёта времени накопления заданного числа токенов
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+// Метод удаления полных корзин: такие ключи не отличаются от новых,
+// поэтому память под них можно освободить
+func (l *Limiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for key, b := range l.buckets {
+		b.refill(now, l.limit)
+		if b.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Метод для получения числа отслеживаемых ключей
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// Метод для периодического удаления полных корзин до отмены контекста
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Cleanup()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Функция создания ограничителя с управляемыми часами
+func newTestLimiter(limit Limit) (*Limiter, func(time.Duration)) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	l := New(limit)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, advance := newTestLimiter(Limit{Rate: 2, Burst: 3})
+
+	for i := 2; i >= 0; i-- {
+		d := l.Allow("collector-1")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 3, d.Limit)
+		assert.Equal(t, i, d.Remaining)
+	}
+	d := l.Allow("collector-1")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+
+	// Другой ключ ограничивается отдельно
+	assert.True(t, l.Allow("collector-2").Allowed)
+
+	advance(500 * time.Millisecond)
+	assert.True(t, l.Allow("collector-1").Allowed)
+	assert.False(t, l.Allow("collector-1").Allowed)
+
+	// Корзина пополняется не больше объёма
+	advance(time.Hour)
+	d = l.Allow("collector-1")
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 2, d.Remaining)
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	l, advance := newTestLimiter(Limit{Rate: 1, Burst: 2})
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+	assert.Equal(t, 2, l.Len())
+
+	advance(time.Second)
+	l.Cleanup()
+	assert.Equal(t, 1, l.Len(), "a is full again, b is not")
+
+	advance(time.Second)
+	l.Cleanup()
+	assert.Equal(t, 0, l.Len())
+}
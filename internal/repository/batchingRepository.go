@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ошибка сохранения ордера после остановки буфера записи
+var ErrClosed = errors.New("write buffer closed")
+
+// Интерфейс хранилища, умеющего сохранять несколько ордеров одной транзакцией.
+// Имя клиента берётся из поля ClientName каждого ордера.
+type BatchSaver interface {
+	SaveOrders(ctx context.Context, orders []*models.HistoryOrder) error
+}
+
+// Параметры буфера записи ордеров
+type BatchOptions struct {
+	// Максимальное число ордеров в одной транзакции
+	MaxBatch int
+	// Ёмкость очереди ордеров, ожидающих записи; при заполнении SaveOrder блокируется
+	BufferSize int
+	// Максимальное время ожидания заполнения пакета
+	FlushInterval time.Duration
+	// Ограничение времени записи одного пакета
+	FlushTimeout time.Duration
+}
+
+// Статистика работы буфера записи ордеров
+type BatchStats struct {
+	Pending   int
+	Capacity  int
+	Batches   uint64
+	Orders    uint64
+	HighWater int
+}
+
+// Ордер, ожидающий записи, и канал для результата его сохранения
+type pendingOrder struct {
+	order  *models.HistoryOrder
+	result chan error
+}
+
+// Структура декоратора репозитория, объединяющего сохранение ордеров в пакеты.
+// Ордера накапливаются до MaxBatch штук или до истечения FlushInterval и
+// записываются одной транзакцией. SaveOrder возвращает управление только
+// после фиксации транзакции, в которую попал ордер.
+type BatchingRepository struct {
+	Repository
+
+	// Пакетная запись хранилища; nil, если хранилище её не поддерживает
+	saver BatchSaver
+	opts  BatchOptions
+
+	// Блокировка защищает отправку в очередь от одновременного закрытия
+	mu       sync.RWMutex
+	closed   bool
+	requests chan *pendingOrder
+	closing  chan struct{}
+	done     chan struct{}
+
+	pending   atomic.Int64
+	highWater atomic.Int64
+	batches   atomic.Uint64
+	orders    atomic.Uint64
+}
+
+// Конструктор для создания буфера записи над репозиторием. Если репозиторий
+// не реализует BatchSaver, ордера пакета сохраняются по одному.
+func NewBatchingRepository(repo Repository, opts BatchOptions) *BatchingRepository {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 1
+	}
+	if opts.BufferSize < 0 {
+		opts.BufferSize = 0
+	}
+	saver, _ := repo.(BatchSaver)
+
+	r := &BatchingRepository{
+		Repository: repo,
+		saver:      saver,
+		opts:       opts,
+		requests:   make(chan *pendingOrder, opts.BufferSize),
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Метод для постановки ордера в очередь на запись. Ожидает фиксации пакета;
+// если контекст отменён после постановки в очередь, ордер всё равно может быть записан.
+func (r *BatchingRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := *order
+	stored.ClientName = client.ClientName
+	p := &pendingOrder{order: &stored, result: make(chan error, 1)}
+
+	if err := r.enqueue(ctx, p); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-p.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *BatchingRepository) enqueue(ctx context.Context, p *pendingOrder) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+
+	pending := r.pending.Add(1)
+	select {
+	case r.requests <- p:
+	case <-ctx.Done():
+		r.pending.Add(-1)
+		return ctx.Err()
+	}
+	for {
+		high := r.highWater.Load()
+		if pending <= high || r.highWater.CompareAndSwap(high, pending) {
+			break
+		}
+	}
+	return nil
+}
+
+// Метод для остановки буфера: новые ордера отклоняются с ErrClosed,
+// а уже поставленные в очередь записываются до возврата управления
+func (r *BatchingRepository) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.closing)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Метод для получения статистики буфера записи
+func (r *BatchingRepository) Stats() BatchStats {
+	return BatchStats{
+		Pending:   int(r.pending.Load()),
+		Capacity:  r.opts.BufferSize,
+		Batches:   r.batches.Load(),
+		Orders:    r.orders.Load(),
+		HighWater: int(r.highWater.Load()),
+	}
+}
+
+// Цикл накопления и записи пакетов
+func (r *BatchingRepository) run() {
+	defer close(r.done)
+
+	batch := make([]*pendingOrder, 0, r.opts.MaxBatch)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		r.flush(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case p := <-r.requests:
+			batch = append(batch, p)
+			if len(batch) >= r.opts.MaxBatch {
+				flush()
+			} else if timer == nil {
+				timer = time.NewTimer(r.opts.FlushInterval)
+				timeout = timer.C
+			}
+		case <-timeout:
+			flush()
+		case <-r.closing:
+			// После закрытия новых отправок нет, дописываем оставшееся в очереди
+			for {
+				select {
+				case p := <-r.requests:
+					batch = append(batch, p)
+					if len(batch) >= r.opts.MaxBatch {
+						flush()
+					}
+				default:
+					if len(batch) > 0 {
+						flush()
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// Метод записи пакета и уведомления ожидающих вызовов
+func (r *BatchingRepository) flush(batch []*pendingOrder) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx := context.Background()
+	if r.opts.FlushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.FlushTimeout)
+		defer cancel()
+	}
+
+	r.batches.Add(1)
+	if r.saver == nil {
+		for _, p := range batch {
+			r.complete(p, r.Repository.SaveOrder(ctx, &models.Client{ClientName: p.order.ClientName}, p.order))
+		}
+		return
+	}
+
+	orders := make([]*models.HistoryOrder, len(batch))
+	for i, p := range batch {
+		orders[i] = p.order
+	}
+	err := r.saver.SaveOrders(ctx, orders)
+
+	// Один некорректный ордер не должен приводить к ошибке для всего пакета,
+	// поэтому при ошибке данных ордера пакета сохраняются по одному
+	if err != nil && len(batch) > 1 && (errors.Is(err, ErrInvalid) || errors.Is(err, ErrConflict)) {
+		for _, p := range batch {
+			err := r.saver.SaveOrders(ctx, []*models.HistoryOrder{p.order})
+			r.complete(p, err)
+		}
+		return
+	}
+	for _, p := range batch {
+		r.complete(p, err)
+	}
+}
+
+func (r *BatchingRepository) complete(p *pendingOrder, err error) {
+	if err == nil {
+		r.orders.Add(1)
+	}
+	r.pending.Add(-1)
+	p.result <- err
+}
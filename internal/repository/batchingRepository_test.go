@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Репозиторий, запоминающий размеры пакетов и позволяющий задержать их запись
+type recordingRepository struct {
+	*InMemoryRepository
+	mu      sync.Mutex
+	batches []int
+	release chan struct{}
+	failOn  string
+}
+
+func (r *recordingRepository) SaveOrders(ctx context.Context, orders []*models.HistoryOrder) error {
+	if r.release != nil {
+		<-r.release
+	}
+	r.mu.Lock()
+	r.batches = append(r.batches, len(orders))
+	r.mu.Unlock()
+	for _, order := range orders {
+		if r.failOn != "" && order.Label == r.failOn {
+			return wrapError(ErrInvalid, errors.New("bad label"))
+		}
+	}
+	return r.InMemoryRepository.SaveOrders(ctx, orders)
+}
+
+func (r *recordingRepository) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.batches...)
+}
+
+func saveConcurrently(repo Repository, n int, label func(i int) string) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order := &models.HistoryOrder{Label: label(i), TimePlaced: time.Now()}
+			errs[i] = repo.SaveOrder(context.Background(), &models.Client{ClientName: "client"}, order)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestBatchingRepository_FlushBySize(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 10, BufferSize: 100, FlushInterval: time.Hour})
+	defer repo.Close(context.Background())
+
+	errs := saveConcurrently(repo, 30, func(i int) string { return fmt.Sprint(i) })
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []int{10, 10, 10}, inner.batchSizes())
+
+	history, err := repo.GetOrderHistory(context.Background(), &models.Client{ClientName: "client"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 30)
+	stats := repo.Stats()
+	assert.Equal(t, uint64(3), stats.Batches)
+	assert.Equal(t, uint64(30), stats.Orders)
+	assert.Equal(t, 0, stats.Pending)
+	assert.GreaterOrEqual(t, stats.HighWater, 10)
+}
+
+func TestBatchingRepository_FlushByInterval(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 100, BufferSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer repo.Close(context.Background())
+
+	start := time.Now()
+	err := repo.SaveOrder(context.Background(), &models.Client{ClientName: "client"}, &models.HistoryOrder{})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, []int{1}, inner.batchSizes())
+}
+
+func TestBatchingRepository_AckAfterCommit(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository(), release: make(chan struct{})}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 1, BufferSize: 1})
+	defer repo.Close(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.SaveOrder(context.Background(), &models.Client{ClientName: "client"}, &models.HistoryOrder{})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("SaveOrder returned before the batch was committed")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(inner.release)
+	assert.NoError(t, <-done)
+}
+
+func TestBatchingRepository_Backpressure(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository(), release: make(chan struct{})}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 1, BufferSize: 1})
+	defer repo.Close(context.Background())
+	client := &models.Client{ClientName: "client"}
+
+	// Первый ордер записывается и блокирует цикл, второй занимает буфер
+	for i := 0; i < 2; i++ {
+		go repo.SaveOrder(context.Background(), client, &models.HistoryOrder{})
+	}
+	assert.Eventually(t, func() bool { return repo.Stats().Pending == 2 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := repo.SaveOrder(ctx, client, &models.HistoryOrder{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, repo.Stats().Pending)
+
+	close(inner.release)
+	assert.Eventually(t, func() bool { return repo.Stats().Pending == 0 }, time.Second, time.Millisecond)
+}
+
+func TestBatchingRepository_InvalidOrderIsolated(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository(), failOn: "bad"}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 5, BufferSize: 5, FlushInterval: time.Hour})
+	defer repo.Close(context.Background())
+
+	errs := saveConcurrently(repo, 5, func(i int) string {
+		if i == 2 {
+			return "bad"
+		}
+		return "good"
+	})
+	for i, err := range errs {
+		if i == 2 {
+			assert.ErrorIs(t, err, ErrInvalid)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	history, err := repo.GetOrderHistory(context.Background(), &models.Client{ClientName: "client"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 4)
+}
+
+func TestBatchingRepository_CloseFlushes(t *testing.T) {
+	inner := &recordingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 100, BufferSize: 100, FlushInterval: time.Hour})
+	client := &models.Client{ClientName: "client"}
+
+	done := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { done <- repo.SaveOrder(context.Background(), client, &models.HistoryOrder{}) }()
+	}
+	assert.Eventually(t, func() bool { return repo.Stats().Pending == 3 }, time.Second, time.Millisecond)
+
+	assert.NoError(t, repo.Close(context.Background()))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, <-done)
+	}
+	assert.Equal(t, []int{3}, inner.batchSizes())
+
+	err := repo.SaveOrder(context.Background(), client, &models.HistoryOrder{})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestBatchingRepository_WithoutBatchSaver(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewBatchingRepository(inner, BatchOptions{MaxBatch: 10, BufferSize: 10, FlushInterval: time.Millisecond})
+	defer repo.Close(context.Background())
+
+	errs := saveConcurrently(repo, 10, func(int) string { return "" })
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	history, err := repo.GetOrderHistory(context.Background(), &models.Client{ClientName: "client"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 10)
+}
+
+func BenchmarkSQLiteSaveOrder(b *testing.B) {
+	order := &models.HistoryOrder{ExchangeName: "Binance", Pair: "BTC/USD", TimePlaced: time.Now()}
+	client := &models.Client{ClientName: "client"}
+
+	b.Run("Direct", func(b *testing.B) {
+		repo := NewSQLiteRepository(setupSQLiteDB(b))
+		b.SetParallelism(64)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := repo.SaveOrder(context.Background(), client, order); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+	b.Run("Batched", func(b *testing.B) {
+		repo := NewBatchingRepository(NewSQLiteRepository(setupSQLiteDB(b)),
+			BatchOptions{MaxBatch: 500, BufferSize: 5000, FlushInterval: time.Millisecond})
+		defer repo.Close(context.Background())
+		b.SetParallelism(64)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := repo.SaveOrder(context.Background(), client, order); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/models"
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Статистика работы кэша книг ордеров
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// Поколение книги ордеров: число её сохранений, пока книга читается из
+// хранилища. Хранится только на время чтений, чтобы число записей не росло
+// вместе с числом ключей.
+type keyGeneration struct {
+	value   uint64
+	fetches int
+}
+
+// Запись кэша книг ордеров
+type cacheEntry struct {
+	key       orderBookKey
+	snapshot  *models.OrderBookSnapshot
+	expiresAt time.Time
+}
+
+// Структура декоратора репозитория, кэширующего чтение книг ордеров.
+// Кэш ограничен по размеру (вытесняются давно не использованные записи)
+// и по времени жизни записей. Одновременные промахи по одному ключу
+// приводят к одному обращению к хранилищу.
+type CachingRepository struct {
+	Repository
+
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	items map[orderBookKey]*list.Element
+	lru   *list.List
+	// Поколения читаемых книг; значения, прочитанные до сохранения книги,
+	// в кэш не попадают
+	generations map[orderBookKey]*keyGeneration
+
+	group     singleflight.Group
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Конструктор для создания кэширующего декоратора над репозиторием
+func NewCachingRepository(repo Repository, capacity int, ttl time.Duration) *CachingRepository {
+	return &CachingRepository{
+		Repository:  repo,
+		capacity:    capacity,
+		ttl:         ttl,
+		now:         time.Now,
+		items:       make(map[orderBookKey]*list.Element),
+		lru:         list.New(),
+		generations: make(map[orderBookKey]*keyGeneration),
+	}
+}
+
+// Метод для получения книги ордеров из кэша или из хранилища
+func (r *CachingRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	snapshot, err := r.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.OrderBook, nil
+}
+
+// Метод для получения книги ордеров с версией из кэша или из хранилища
+func (r *CachingRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Клиенту, запросившему чтение своих записей, отдаются данные основного сервера
+	if db.PrimaryRequested(ctx) {
+		return r.Repository.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	}
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+	if snapshot, ok := r.lookup(key); ok {
+		r.hits.Add(1)
+		return copySnapshot(snapshot), nil
+	}
+	r.misses.Add(1)
+
+	// Поколение входит в ключ, чтобы вызовы после сохранения не получили
+	// результат запроса, начатого до него
+	r.mu.Lock()
+	var generation uint64
+	if g := r.generations[key]; g != nil {
+		generation = g.value
+	}
+	r.mu.Unlock()
+
+	flightKey := fmt.Sprintf("%s\x00%s\x00%d", exchangeName, pair, generation)
+	ch := r.group.DoChan(flightKey, func() (interface{}, error) {
+		// Общий запрос не должен прерываться, если отменён только первый из ожидающих
+		// вызовов, поэтому он выполняется с контекстом без отмены, но с тем же дедлайном
+		fetchCtx, cancel := detachedContext(ctx)
+		defer cancel()
+
+		g, fetched := r.beginFetch(key)
+		defer r.endFetch(key, g)
+		snapshot, err := r.Repository.GetOrderBookSnapshot(fetchCtx, exchangeName, pair)
+		if err != nil {
+			return nil, err
+		}
+		r.storeIfCurrent(key, snapshot, g, fetched)
+		return snapshot, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copySnapshot(res.Val.(*models.OrderBookSnapshot)), nil
+	}
+}
+
+// Функция создания контекста, который не отменяется вместе с родительским,
+// но сохраняет его значения и дедлайн
+func detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// Метод для сохранения книги ордеров с удалением её из кэша
+func (r *CachingRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	_, err := r.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, 0)
+	return err
+}
+
+// Метод для сохранения книги ордеров с проверкой версии. Сохранённое значение
+// в кэш не записывается: одновременные сохранения могут завершиться в другом
+// порядке, чем были зафиксированы, и более старая версия заменила бы новую.
+// Запись удаляется из кэша до и после сохранения, поэтому чтения, начатые
+// до фиксации, не вернут в кэш прежнее значение.
+func (r *CachingRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+	r.invalidate(key)
+	defer r.invalidate(key)
+	return r.Repository.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion)
+}
+
+// Функция копирования снимка, чтобы вызывающий код не изменял данные кэша
+func copySnapshot(snapshot *models.OrderBookSnapshot) *models.OrderBookSnapshot {
+	copied := *snapshot
+	copied.OrderBook = copyDepthOrders(snapshot.OrderBook)
+	return &copied
+}
+
+// Метод для выполнения операций в транзакции. Чтение внутри транзакции идёт
+// в обход кэша, а книги ордеров, сохранённые в ней, удаляются из кэша после её завершения.
+func (r *CachingRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	tx := &cachingTx{}
+	defer func() {
+		for _, key := range tx.saved {
+			r.invalidate(key)
+		}
+	}()
+	return r.Repository.WithTx(ctx, func(inner Repository) error {
+		tx.Repository = inner
+		return fn(tx)
+	})
+}
+
+// Транзакция кэширующего репозитория, запоминающая сохранённые книги ордеров
+type cachingTx struct {
+	Repository
+	mu    sync.Mutex
+	saved []orderBookKey
+}
+
+func (tx *cachingTx) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	tx.remember(exchangeName, pair)
+	return tx.Repository.SaveOrderBook(ctx, exchangeName, pair, orderBook)
+}
+
+func (tx *cachingTx) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	tx.remember(exchangeName, pair)
+	return tx.Repository.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion)
+}
+
+func (tx *cachingTx) remember(exchangeName, pair string) {
+	tx.mu.Lock()
+	tx.saved = append(tx.saved, orderBookKey{exchange: exchangeName, pair: pair})
+	tx.mu.Unlock()
+}
+
+// Метод для получения статистики кэша
+func (r *CachingRepository) Stats() CacheStats {
+	r.mu.Lock()
+	size := r.lru.Len()
+	r.mu.Unlock()
+	return CacheStats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.evictions.Load(),
+		Size:      size,
+	}
+}
+
+func (r *CachingRepository) lookup(key orderBookKey) (*models.OrderBookSnapshot, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if r.ttl > 0 && !r.now().Before(entry.expiresAt) {
+		r.remove(elem)
+		return nil, false
+	}
+	r.lru.MoveToFront(elem)
+	return entry.snapshot, true
+}
+
+// Метод начала чтения книги из хранилища; возвращает её поколение
+func (r *CachingRepository) beginFetch(key orderBookKey) (*keyGeneration, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g := r.generations[key]
+	if g == nil {
+		g = &keyGeneration{}
+		r.generations[key] = g
+	}
+	g.fetches++
+	return g, g.value
+}
+
+// Метод завершения чтения книги; поколение удаляется после последнего чтения
+func (r *CachingRepository) endFetch(key orderBookKey, g *keyGeneration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g.fetches--; g.fetches == 0 {
+		delete(r.generations, key)
+	}
+}
+
+func (r *CachingRepository) storeIfCurrent(key orderBookKey, snapshot *models.OrderBookSnapshot, g *keyGeneration, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g.value != generation {
+		return
+	}
+	r.store(key, copySnapshot(snapshot))
+}
+
+// Метод добавления записи; вызывается с захваченной блокировкой
+func (r *CachingRepository) store(key orderBookKey, snapshot *models.OrderBookSnapshot) {
+	if r.capacity <= 0 {
+		return
+	}
+	entry := &cacheEntry{key: key, snapshot: snapshot, expiresAt: r.now().Add(r.ttl)}
+	if elem, ok := r.items[key]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.items[key] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.capacity {
+		r.remove(r.lru.Back())
+		r.evictions.Add(1)
+	}
+}
+
+func (r *CachingRepository) invalidate(key orderBookKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Книга без текущих чтений не может попасть в кэш прежним значением
+	if g := r.generations[key]; g != nil {
+		g.value++
+	}
+	if elem, ok := r.items[key]; ok {
+		r.remove(elem)
+	}
+}
+
+// Метод удаления записи; вызывается с захваченной блокировкой
+func (r *CachingRepository) remove(elem *list.Element) {
+	r.lru.Remove(elem)
+	delete(r.items, elem.Value.(*cacheEntry).key)
+}
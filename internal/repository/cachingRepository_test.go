@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Репозиторий, считающий чтения книг ордеров и позволяющий задержать их
+type countingRepository struct {
+	*InMemoryRepository
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (r *countingRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.InMemoryRepository.GetOrderBookSnapshot(ctx, exchangeName, pair)
+}
+
+func TestCachingRepository_HitsAndMisses(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+
+	orderBook := []*models.DepthOrder{{Price: 1, BaseQty: 1}, {Price: 2, BaseQty: 2}}
+	assert.NoError(t, inner.SaveOrderBook(ctx, "Binance", "BTC/USD", orderBook))
+
+	for i := 0; i < 3; i++ {
+		result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+		assert.NoError(t, err)
+		assert.Equal(t, orderBook, result)
+	}
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Size: 1}, repo.Stats())
+
+	_, err := repo.GetOrderBook(ctx, "Binance", "ETH/USD")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.GetOrderBook(ctx, "Binance", "ETH/USD")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(3), inner.calls.Load(), "errors must not be cached")
+}
+
+func TestCachingRepository_TTL(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewCachingRepository(inner, 10, time.Second)
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NoError(t, inner.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+
+	now = now.Add(500 * time.Millisecond)
+	_, err = repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), inner.calls.Load())
+
+	now = now.Add(time.Second)
+	_, err = repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
+
+func TestCachingRepository_Eviction(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewCachingRepository(inner, 2, time.Minute)
+	ctx := context.Background()
+
+	for _, pair := range []string{"A", "B", "C"} {
+		assert.NoError(t, inner.SaveOrderBook(ctx, "Binance", pair, []*models.DepthOrder{{Price: 1}}))
+	}
+
+	for _, pair := range []string{"A", "B", "A", "C", "A", "B"} {
+		_, err := repo.GetOrderBook(ctx, "Binance", pair)
+		assert.NoError(t, err)
+	}
+
+	stats := repo.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, int32(4), inner.calls.Load(), "A must stay cached as the most recently used key")
+}
+
+func TestCachingRepository_InvalidatesOnSave(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.Stats().Size)
+
+	updated := []*models.DepthOrder{{Price: 2}}
+	assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USD", updated))
+	assert.Equal(t, 0, repo.Stats().Size)
+	updated[0].Price = 3
+
+	snapshot, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.DepthOrder{{Price: 2}}, snapshot.OrderBook)
+	assert.Equal(t, int64(2), snapshot.Version)
+	assert.Equal(t, int32(2), inner.calls.Load())
+
+	// Несовпадение версии тоже удаляет запись, чтобы следующее чтение получило актуальную версию
+	_, err = repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	_, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USD", nil, 1)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.Equal(t, 0, repo.Stats().Size)
+}
+
+// Репозиторий, задерживающий возврат первой прочитанной книги ордеров
+type slowReadRepository struct {
+	*InMemoryRepository
+	read    chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (r *slowReadRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	snapshot, err := r.InMemoryRepository.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	r.once.Do(func() {
+		close(r.read)
+		<-r.release
+	})
+	return snapshot, err
+}
+
+// Чтение, начатое до фиксации сохранения, не должно вернуть в кэш прежнюю версию
+func TestCachingRepository_ReadDuringSave(t *testing.T) {
+	inner := &slowReadRepository{InMemoryRepository: NewInMemoryRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+	assert.NoError(t, inner.InMemoryRepository.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+
+	read := make(chan *models.OrderBookSnapshot)
+	go func() {
+		snapshot, _ := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+		read <- snapshot
+	}()
+	// Чтение получило версию 1, после чего сохраняется версия 2
+	<-inner.read
+	assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 2}}))
+	close(inner.release)
+	assert.Equal(t, int64(1), (<-read).Version)
+
+	snapshot, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Version)
+}
+
+// Сохранение другой книги не мешает кэшировать прочитанную
+func TestCachingRepository_SaveOtherKeyDuringRead(t *testing.T) {
+	inner := &slowReadRepository{InMemoryRepository: NewInMemoryRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+	assert.NoError(t, inner.InMemoryRepository.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+
+	read := make(chan struct{})
+	go func() {
+		repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+		close(read)
+	}()
+	<-inner.read
+	assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", "ETH/USD", []*models.DepthOrder{{Price: 2}}))
+	close(inner.release)
+	<-read
+
+	_, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), repo.Stats().Hits)
+	assert.Empty(t, repo.generations, "generations are kept only while books are read")
+}
+
+func TestCachingRepository_Singleflight(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository(), release: make(chan struct{})}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+	assert.NoError(t, inner.InMemoryRepository.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+			assert.NoError(t, err)
+			assert.Len(t, result, 1)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return inner.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func TestCachingRepository_CancelledWaiter(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository(), release: make(chan struct{})}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	assert.NoError(t, inner.InMemoryRepository.SaveOrderBook(context.Background(), "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return inner.calls.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	close(inner.release)
+	result, err := repo.GetOrderBook(context.Background(), "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+}
+
+func TestCachingRepository_PrimaryBypassesCache(t *testing.T) {
+	inner := &countingRepository{InMemoryRepository: NewInMemoryRepository()}
+	repo := NewCachingRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+	assert.NoError(t, inner.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: 1}}))
+
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	_, err = repo.GetOrderBook(db.WithPrimary(ctx), "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"StatisticsCollectionService/internal/repository"
+	"StatisticsCollectionService/internal/repository/repositorytest"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewInMemoryRepository()
+	})
+}
+
+func TestSQLiteRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewSQLiteRepository(repository.SetupSQLiteDB(t))
+	})
+}
+
+func TestPostgresRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		db := repository.SetupTestDB(t)
+		t.Cleanup(func() { repository.TeardownTestDB(t, db) })
+		return repository.NewPostgresRepository(db)
+	})
+}
+
+func TestCachingRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewCachingRepository(repository.NewInMemoryRepository(), 16, time.Minute)
+	})
+}
+
+func TestBatchingRepository_Conformance(t *testing.T) {
+	opts := repository.BatchOptions{MaxBatch: 64, BufferSize: 256, FlushInterval: 100 * time.Microsecond, FlushTimeout: time.Second}
+	newBatching := func(t *testing.T, repo repository.Repository) repository.Repository {
+		batching := repository.NewBatchingRepository(repo, opts)
+		t.Cleanup(func() { batching.Close(context.Background()) })
+		return batching
+	}
+
+	t.Run("InMemory", func(t *testing.T) {
+		repositorytest.Run(t, func(t *testing.T) repository.Repository {
+			return newBatching(t, repository.NewInMemoryRepository())
+		})
+	})
+	t.Run("SQLite", func(t *testing.T) {
+		repositorytest.Run(t, func(t *testing.T) repository.Repository {
+			return newBatching(t, repository.NewSQLiteRepository(repository.SetupSQLiteDB(t)))
+		})
+	})
+}
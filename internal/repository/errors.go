@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Доменные ошибки хранилища. Реализации Repository оборачивают ошибки драйверов
+// так, чтобы errors.Is с этими значениями позволял определить причину.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrInvalid     = errors.New("invalid data")
+	ErrUnavailable = errors.New("storage unavailable")
+	// Версия книги ордеров отличается от ожидаемой при условном сохранении
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+// Функция для оборачивания ошибки в доменную с сохранением исходной причины
+func wrapError(kind, err error) error {
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// Функция преобразования ошибок PostgreSQL в доменные
+func postgresError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return wrapError(ErrNotFound, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23502" || pqErr.Code == "23514":
+			return wrapError(ErrInvalid, err)
+		case pqErr.Code.Class() == "23" || pqErr.Code.Class() == "40":
+			return wrapError(ErrConflict, err)
+		case pqErr.Code.Class() == "22":
+			return wrapError(ErrInvalid, err)
+		case pqErr.Code.Class() == "08" || pqErr.Code.Class() == "53" || pqErr.Code.Class() == "57":
+			return wrapError(ErrUnavailable, err)
+		}
+		return err
+	}
+	return connectionError(err)
+}
+
+// Функция преобразования ошибок SQLite в доменные
+func sqliteError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return wrapError(ErrNotFound, err)
+	}
+
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		switch liteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CONSTRAINT:
+			return wrapError(ErrConflict, err)
+		case sqlite3.SQLITE_TOOBIG, sqlite3.SQLITE_MISMATCH:
+			return wrapError(ErrInvalid, err)
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN:
+			return wrapError(ErrUnavailable, err)
+		}
+		return err
+	}
+	return connectionError(err)
+}
+
+// Функция распознавания ошибок соединения с базой данных
+func connectionError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		strings.Contains(err.Error(), "connection refused") {
+		return wrapError(ErrUnavailable, err)
+	}
+	return err
+}
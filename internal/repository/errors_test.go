@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresError(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{&pq.Error{Code: "23505"}, ErrConflict},
+		{&pq.Error{Code: "40001"}, ErrConflict},
+		{&pq.Error{Code: "23502"}, ErrInvalid},
+		{&pq.Error{Code: "22001"}, ErrInvalid},
+		{&pq.Error{Code: "08006"}, ErrUnavailable},
+		{&pq.Error{Code: "57P03"}, ErrUnavailable},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		err := postgresError(tt.err)
+		assert.ErrorIs(t, err, tt.kind, "%v", tt.err)
+		assert.ErrorIs(t, err, tt.err, "original error must be preserved")
+	}
+
+	other := &pq.Error{Code: "42P01"}
+	assert.Equal(t, other, postgresError(other))
+	assert.Equal(t, context.Canceled, postgresError(context.Canceled))
+	assert.ErrorIs(t, postgresError(fmt.Errorf("query: %w", context.DeadlineExceeded)), context.DeadlineExceeded)
+	assert.NoError(t, postgresError(nil))
+}
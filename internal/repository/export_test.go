@@ -0,0 +1,8 @@
+package repository
+
+// Экспорт вспомогательных функций для тестов во внешнем пакете repository_test
+var (
+	SetupTestDB    = setupTestDB
+	TeardownTestDB = teardownTestDB
+	SetupSQLiteDB  = setupSQLiteDB
+)
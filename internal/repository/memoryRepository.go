@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// Ключ книги ордеров в памяти
+type orderBookKey struct {
+	exchange string
+	pair     string
+}
+
+// Книга ордеров, хранящаяся в памяти
+type memoryOrderBook struct {
+	asks      []*models.DepthOrder
+	bids      []*models.DepthOrder
+	version   int64
+	updatedAt time.Time
+}
+
+// Функция создания хранимой книги ордеров следующей версии. Как и в
+// PostgreSQL, первая половина переданных ордеров сохраняется как asks,
+// вторая — как bids.
+func newMemoryOrderBook(orderBook []*models.DepthOrder, version int64) memoryOrderBook {
+	return memoryOrderBook{
+		asks:      copyDepthOrders(orderBook[:len(orderBook)/2]),
+		bids:      copyDepthOrders(orderBook[len(orderBook)/2:]),
+		version:   version,
+		updatedAt: normalizeTime(time.Now()),
+	}
+}
+
+// Метод получения снимка хранимой книги ордеров
+func (b memoryOrderBook) snapshot() *models.OrderBookSnapshot {
+	return &models.OrderBookSnapshot{
+		OrderBook: append(copyDepthOrders(b.asks), copyDepthOrders(b.bids)...),
+		Version:   b.version,
+		UpdatedAt: b.updatedAt,
+	}
+}
+
+// Структура репозитория, хранящего данные в памяти процесса.
+// Безопасна для конкурентного использования.
+type InMemoryRepository struct {
+	mu         sync.RWMutex
+	orderBooks map[orderBookKey]memoryOrderBook
+	history    []models.HistoryOrder
+}
+
+// Конструктор для создания нового репозитория в памяти
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{orderBooks: make(map[orderBookKey]memoryOrderBook)}
+}
+
+// Метод для получения книги ордеров
+func (r *InMemoryRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	snapshot, err := r.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.OrderBook, nil
+}
+
+// Метод для получения книги ордеров с версией и временем сохранения
+func (r *InMemoryRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	book, ok := r.orderBooks[orderBookKey{exchange: exchangeName, pair: pair}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return book.snapshot(), nil
+}
+
+// Метод для сохранения книги ордеров
+func (r *InMemoryRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	_, err := r.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, 0)
+	return err
+}
+
+// Метод для сохранения книги ордеров с увеличением её версии. При
+// expectedVersion больше нуля книга сохраняется, только если её версия
+// совпадает с ожидаемой, при AnyVersion — только если книга существует.
+func (r *InMemoryRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.orderBooks[key]
+	if !versionMatches(current.version, expectedVersion) {
+		return nil, ErrVersionMismatch
+	}
+	book := newMemoryOrderBook(orderBook, current.version+1)
+	r.orderBooks[key] = book
+	return book.snapshot(), nil
+}
+
+// Метод для восстановления книги ордеров из архива с исходным временем
+// сохранения. Более новая книга, сохранённая после архивации, не заменяется.
+func (r *InMemoryRepository) RestoreOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, updatedAt time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.orderBooks[key]
+	book, restored := restoredOrderBook(current, ok, orderBook, updatedAt)
+	if restored {
+		r.orderBooks[key] = book
+	}
+	return restored, nil
+}
+
+// Функция создания книги ордеров, восстановленной из архива поверх
+// текущей книги current; возвращает false, если current не старше архивной
+func restoredOrderBook(current memoryOrderBook, exists bool, orderBook []*models.DepthOrder, updatedAt time.Time) (memoryOrderBook, bool) {
+	updatedAt = normalizeTime(updatedAt)
+	if exists && !current.updatedAt.Before(updatedAt) {
+		return current, false
+	}
+	book := newMemoryOrderBook(orderBook, current.version+1)
+	book.updatedAt = updatedAt
+	return book, true
+}
+
+// Метод для получения истории ордеров клиента за запрошенный период в порядке сохранения
+func (r *InMemoryRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []*models.HistoryOrder
+	for i := range r.history {
+		if r.history[i].ClientName == client.ClientName && inHistoryPeriod(client, &r.history[i]) {
+			order := r.history[i]
+			orders = append(orders, &order)
+		}
+	}
+	return orders, nil
+}
+
+// Метод для сохранения ордера. Имя клиента берётся из client, как и в PostgreSQL.
+func (r *InMemoryRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := *order
+	stored.ClientName = client.ClientName
+	stored.TimePlaced = normalizeTime(order.TimePlaced)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history = append(r.history, stored)
+	return nil
+}
+
+// Функция копирования ордеров, чтобы вызывающий код не изменял хранимые данные
+func copyDepthOrders(orders []*models.DepthOrder) []*models.DepthOrder {
+	copied := make([]*models.DepthOrder, len(orders))
+	for i, order := range orders {
+		if order != nil {
+			o := *order
+			copied[i] = &o
+		}
+	}
+	return copied
+}
+
+// Метод для пакетного сохранения ордеров; ордера добавляются в историю атомарно
+func (r *InMemoryRepository) SaveOrders(ctx context.Context, orders []*models.HistoryOrder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := make([]models.HistoryOrder, len(orders))
+	for i, order := range orders {
+		stored[i] = *order
+		stored[i].TimePlaced = normalizeTime(order.TimePlaced)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history = append(r.history, stored...)
+	return nil
+}
+
+// Метод для выполнения нескольких операций в одной транзакции. Изменения,
+// сделанные через переданный в fn репозиторий, видны только ему и применяются
+// к хранилищу атомарно, если fn завершилась без ошибки.
+func (r *InMemoryRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx := &memoryTx{
+		repo:       r,
+		orderBooks: make(map[orderBookKey]memoryOrderBook),
+		expected:   make(map[orderBookKey]int64),
+		restored:   make(map[orderBookKey]bool),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	// Как и в базе данных, отменённый контекст не даёт зафиксировать транзакцию
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Условные сохранения проверяются повторно: книгу могли сохранить вне
+	// транзакции после проверки версии
+	for key, expected := range tx.expected {
+		if !versionMatches(r.orderBooks[key].version, expected) {
+			return ErrVersionMismatch
+		}
+	}
+	for key, book := range tx.orderBooks {
+		current, ok := r.orderBooks[key]
+		// Восстановленная из архива книга не заменяет сохранённую позже
+		if tx.restored[key] && ok && !current.updatedAt.Before(book.updatedAt) {
+			continue
+		}
+		// Версия не должна уменьшаться, если книгу сохранили вне транзакции
+		if current.version >= book.version {
+			book.version = current.version + 1
+		}
+		r.orderBooks[key] = book
+	}
+	r.history = append(r.history, tx.history...)
+	return nil
+}
+
+// Транзакция хранилища в памяти: накапливает изменения до фиксации
+type memoryTx struct {
+	repo       *InMemoryRepository
+	mu         sync.RWMutex
+	orderBooks map[orderBookKey]memoryOrderBook
+	history    []models.HistoryOrder
+	// Ожидаемые версии книг хранилища, проверяемые повторно при фиксации
+	expected map[orderBookKey]int64
+	// Книги, восстановленные из архива и не изменённые после этого
+	restored map[orderBookKey]bool
+}
+
+func (tx *memoryTx) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	snapshot, err := tx.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.OrderBook, nil
+}
+
+func (tx *memoryTx) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx.mu.RLock()
+	book, ok := tx.orderBooks[orderBookKey{exchange: exchangeName, pair: pair}]
+	tx.mu.RUnlock()
+	if !ok {
+		return tx.repo.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	}
+	return book.snapshot(), nil
+}
+
+func (tx *memoryTx) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	_, err := tx.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, 0)
+	return err
+}
+
+func (tx *memoryTx) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	current, ok := tx.orderBooks[key]
+	if !ok {
+		tx.repo.mu.RLock()
+		current = tx.repo.orderBooks[key]
+		tx.repo.mu.RUnlock()
+		if expectedVersion != 0 {
+			tx.expected[key] = expectedVersion
+		}
+	}
+	if !versionMatches(current.version, expectedVersion) {
+		return nil, ErrVersionMismatch
+	}
+	book := newMemoryOrderBook(orderBook, current.version+1)
+	tx.orderBooks[key] = book
+	delete(tx.restored, key)
+	return book.snapshot(), nil
+}
+
+func (tx *memoryTx) RestoreOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, updatedAt time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	key := orderBookKey{exchange: exchangeName, pair: pair}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	current, ok := tx.orderBooks[key]
+	if !ok {
+		tx.repo.mu.RLock()
+		current, ok = tx.repo.orderBooks[key]
+		tx.repo.mu.RUnlock()
+	}
+	book, restored := restoredOrderBook(current, ok, orderBook, updatedAt)
+	if restored {
+		tx.orderBooks[key] = book
+		tx.restored[key] = true
+	}
+	return restored, nil
+}
+
+func (tx *memoryTx) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	orders, err := tx.repo.GetOrderHistory(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+	for i := range tx.history {
+		if tx.history[i].ClientName == client.ClientName && inHistoryPeriod(client, &tx.history[i]) {
+			order := tx.history[i]
+			orders = append(orders, &order)
+		}
+	}
+	return orders, nil
+}
+
+func (tx *memoryTx) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := *order
+	stored.ClientName = client.ClientName
+	stored.TimePlaced = normalizeTime(order.TimePlaced)
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.history = append(tx.history, stored)
+	return nil
+}
+
+// Вложенная транзакция выполняется в рамках текущей
+func (tx *memoryTx) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return fn(tx)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryRepository_ReturnsCopies(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+
+	orderBook := []*models.DepthOrder{
+		{Price: 10000.0, BaseQty: 1.0},
+		{Price: 10500.0, BaseQty: 2.0},
+	}
+	assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USD", orderBook))
+	orderBook[0].Price = 1
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 10000.0, result[0].Price)
+
+	result[1].Price = 2
+	result, err = repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 10500.0, result[1].Price)
+}
+
+// Книга, сохранённая во время транзакции восстановления, не заменяется архивной
+func TestInMemoryRepository_RestoreInTx(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	live := []*models.DepthOrder{{Price: 2, BaseQty: 2}}
+
+	err := repo.WithTx(ctx, func(tx Repository) error {
+		restored, err := tx.(OrderBookRestorer).RestoreOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.True(t, restored)
+		return repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", live)
+	})
+	require.NoError(t, err)
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, live, result)
+}
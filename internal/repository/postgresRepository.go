@@ -1,99 +1,165 @@
 package repository
 
 import (
+	"StatisticsCollectionService/internal/db"
 	"StatisticsCollectionService/internal/models"
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // Структура репозитория для работы с PostgreSQL
 type PostgresRepository struct {
-	db *sql.DB
+	db querier
+	// Пул соединений для начала транзакций; nil внутри транзакции
+	conn *sql.DB
+	// Реплики для чтения; nil, если реплики не настроены, и внутри транзакции
+	cluster *db.Cluster
 }
 
 // Конструктор для создания нового репозитория
 func NewPostgresRepository(db *sql.DB) *PostgresRepository {
-	return &PostgresRepository{db: db}
+	return &PostgresRepository{db: traced(db, semconv.DBSystemPostgreSQL), conn: db}
 }
 
-// Метод для получения книги ордеров из базы данных
-func (r *PostgresRepository) GetOrderBook(exchangeName, pair string) ([]*models.DepthOrder, error) {
-	query := `SELECT asks, bids FROM order_books WHERE exchange = $1 AND pair = $2`
-	row := r.db.QueryRow(query, exchangeName, pair)
+// Конструктор для создания репозитория, читающего с реплик. Запись и транзакции
+// выполняются на основном сервере кластера.
+func NewReplicatedPostgresRepository(cluster *db.Cluster) *PostgresRepository {
+	return &PostgresRepository{db: traced(cluster.Primary(), semconv.DBSystemPostgreSQL), conn: cluster.Primary(), cluster: cluster}
+}
 
-	var asksJSON, bidsJSON []byte
-	err := row.Scan(&asksJSON, &bidsJSON)
+// Метод выполнения чтения: на реплике, если они настроены, иначе в текущем подключении
+func (r *PostgresRepository) read(ctx context.Context, fn func(q querier) error) error {
+	if r.cluster == nil {
+		return fn(r.db)
+	}
+	unavailable := func(err error) bool { return errors.Is(err, ErrUnavailable) }
+	return r.cluster.Read(ctx, unavailable, func(conn *sql.DB) error { return fn(traced(conn, semconv.DBSystemPostgreSQL)) })
+}
+
+// Метод для получения книги ордеров из базы данных
+func (r *PostgresRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	snapshot, err := r.GetOrderBookSnapshot(ctx, exchangeName, pair)
 	if err != nil {
 		return nil, err
 	}
+	return snapshot.OrderBook, nil
+}
 
-	var asks, bids []*models.DepthOrder
-	err = json.Unmarshal(asksJSON, &asks)
+// Метод для получения книги ордеров с версией и временем сохранения
+func (r *PostgresRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	query := `SELECT asks, bids, version, updated_at FROM order_books WHERE exchange = $1 AND pair = $2`
+
+	var snapshot models.OrderBookSnapshot
+	var asksJSON, bidsJSON []byte
+	err := r.read(ctx, func(q querier) error {
+		return postgresError(q.QueryRowContext(ctx, query, exchangeName, pair).Scan(&asksJSON, &bidsJSON, &snapshot.Version, &snapshot.UpdatedAt))
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(bidsJSON, &bids)
+
+	orderBook, err := unmarshalOrderBook(asksJSON, bidsJSON)
 	if err != nil {
 		return nil, err
 	}
-
-	return append(asks, bids...), nil
+	snapshot.OrderBook = orderBook
+	snapshot.UpdatedAt = snapshot.UpdatedAt.UTC()
+	return &snapshot, nil
 }
 
 // Метод для сохранения книги ордеров в базе данных
-func (r *PostgresRepository) SaveOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error {
-	asksJSON, err := json.Marshal(orderBook[:len(orderBook)/2])
+func (r *PostgresRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	_, err := r.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, 0)
+	return err
+}
+
+// Метод для сохранения книги ордеров с увеличением её версии. При
+// expectedVersion больше нуля книга обновляется, только если её версия не
+// изменилась с момента чтения, при AnyVersion — только если книга существует.
+func (r *PostgresRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	asksJSON, bidsJSON, err := marshalOrderBook(orderBook)
 	if err != nil {
-		return err
+		return nil, wrapError(ErrInvalid, err)
+	}
+	snapshot := &models.OrderBookSnapshot{OrderBook: orderBook, UpdatedAt: normalizeTime(time.Now())}
+	if expectedVersion != 0 {
+		query := `UPDATE order_books SET asks = $3, bids = $4, updated_at = $5, version = version + 1 WHERE exchange = $1 AND pair = $2`
+		args := []interface{}{exchangeName, pair, asksJSON, bidsJSON, snapshot.UpdatedAt}
+		if expectedVersion != AnyVersion {
+			query += ` AND version = $6`
+			args = append(args, expectedVersion)
+		}
+		err = r.db.QueryRowContext(ctx, query+` RETURNING version`, args...).Scan(&snapshot.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionMismatch
+		}
+	} else {
+		query := `INSERT INTO order_books (exchange, pair, asks, bids, updated_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (exchange, pair) DO UPDATE SET asks = EXCLUDED.asks, bids = EXCLUDED.bids, updated_at = EXCLUDED.updated_at, version = order_books.version + 1 RETURNING version`
+		err = r.db.QueryRowContext(ctx, query, exchangeName, pair, asksJSON, bidsJSON, snapshot.UpdatedAt).Scan(&snapshot.Version)
 	}
-	bidsJSON, err := json.Marshal(orderBook[len(orderBook)/2:])
 	if err != nil {
-		return err
+		return nil, postgresError(err)
 	}
-	query := `INSERT INTO order_books (exchange, pair, asks, bids) VALUES ($1, $2, $3, $4) ON CONFLICT (exchange, pair) DO UPDATE SET asks = EXCLUDED.asks, bids = EXCLUDED.bids`
-	_, err = r.db.Exec(query, exchangeName, pair, asksJSON, bidsJSON)
-	return err
+	return snapshot, nil
 }
 
-// Метод для получения истории ордеров из базы данных
-func (r *PostgresRepository) GetOrderHistory(client *models.Client) ([]*models.HistoryOrder, error) {
-	query := `SELECT client_name, exchange_name, label, pair, side, type, base_qty, price, algorithm_name_placed, lowest_sell_prc, highest_buy_prc, commission_quote_qty, time_placed FROM order_history WHERE client_name = $1`
-	rows, err := r.db.Query(query, client.ClientName)
+// Метод для восстановления книги ордеров из архива с исходным временем
+// сохранения. Более новая книга, сохранённая после архивации, не заменяется.
+func (r *PostgresRepository) RestoreOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, updatedAt time.Time) (bool, error) {
+	asksJSON, bidsJSON, err := marshalOrderBook(orderBook)
 	if err != nil {
-		return nil, err
+		return false, wrapError(ErrInvalid, err)
 	}
-	defer rows.Close()
+	query := `INSERT INTO order_books (exchange, pair, asks, bids, updated_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (exchange, pair) DO UPDATE SET asks = EXCLUDED.asks, bids = EXCLUDED.bids, updated_at = EXCLUDED.updated_at, version = order_books.version + 1 WHERE order_books.updated_at < EXCLUDED.updated_at`
+	result, err := r.db.ExecContext(ctx, query, exchangeName, pair, asksJSON, bidsJSON, normalizeTime(updatedAt))
+	if err != nil {
+		return false, postgresError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, postgresError(err)
+	}
+	return n > 0, nil
+}
+
+// Метод для получения истории ордеров из базы данных в порядке сохранения
+func (r *PostgresRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	query, args := historyQuery(client)
 
 	var orders []*models.HistoryOrder
-	for rows.Next() {
-		var order models.HistoryOrder
-		err := rows.Scan(
-			&order.ClientName,
-			&order.ExchangeName,
-			&order.Label,
-			&order.Pair,
-			&order.Side,
-			&order.Type,
-			&order.BaseQty,
-			&order.Price,
-			&order.AlgorithmNamePlaced,
-			&order.LowestSellPrice,
-			&order.HighestBuyPrice,
-			&order.CommissionQuoteQty,
-			&order.TimePlaced,
-		)
+	err := r.read(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx, query, args...)
 		if err != nil {
-			return nil, err
+			return postgresError(err)
 		}
-		orders = append(orders, &order)
+		defer rows.Close()
+
+		orders = nil
+		for rows.Next() {
+			order, err := scanHistoryOrder(rows)
+			if err != nil {
+				return postgresError(err)
+			}
+			order.TimePlaced = order.TimePlaced.UTC()
+			orders = append(orders, order)
+		}
+		return postgresError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
 	}
 	return orders, nil
 }
 
 // Метод для сохранения ордера в базе данных
-func (r *PostgresRepository) SaveOrder(client *models.Client, order *models.HistoryOrder) error {
+func (r *PostgresRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
 	query := `INSERT INTO order_history (client_name, exchange_name, label, pair, side, type, base_qty, price, algorithm_name_placed, lowest_sell_prc, highest_buy_prc, commission_quote_qty, time_placed) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
-	_, err := r.db.Exec(query,
+	_, err := r.db.ExecContext(ctx, query,
 		client.ClientName,
 		order.ExchangeName,
 		order.Label,
@@ -106,7 +172,42 @@ func (r *PostgresRepository) SaveOrder(client *models.Client, order *models.Hist
 		order.LowestSellPrice,
 		order.HighestBuyPrice,
 		order.CommissionQuoteQty,
-		order.TimePlaced,
+		normalizeTime(order.TimePlaced),
 	)
-	return err
+	return postgresError(err)
+}
+
+// Метод для пакетного сохранения ордеров одной транзакцией через COPY
+func (r *PostgresRepository) SaveOrders(ctx context.Context, orders []*models.HistoryOrder) error {
+	return r.withTx(ctx, func(tx *PostgresRepository) error {
+		stmt, err := tx.db.PrepareContext(ctx, pq.CopyIn("order_history", historyColumns...))
+		if err != nil {
+			return postgresError(err)
+		}
+		defer stmt.Close()
+
+		for _, order := range orders {
+			if _, err := stmt.ExecContext(ctx, historyValues(order)...); err != nil {
+				return postgresError(err)
+			}
+		}
+		_, err = stmt.ExecContext(ctx)
+		return postgresError(err)
+	})
+}
+
+// Метод для выполнения нескольких операций в одной транзакции. Репозиторий,
+// переданный в fn, выполняет запросы в транзакции; вложенный вызов WithTx
+// использует уже открытую транзакцию.
+func (r *PostgresRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return r.withTx(ctx, func(tx *PostgresRepository) error { return fn(tx) })
+}
+
+func (r *PostgresRepository) withTx(ctx context.Context, fn func(tx *PostgresRepository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+	return runTx(ctx, r.conn, postgresError, func(tx *sql.Tx) error {
+		return fn(&PostgresRepository{db: traced(tx, semconv.DBSystemPostgreSQL)})
+	})
 }
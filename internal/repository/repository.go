@@ -1,10 +1,49 @@
 package repository
 
-import "StatisticsCollectionService/internal/models"
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"time"
+)
+
+// Ожидаемая версия, при которой книга ордеров сохраняется, только если
+// она уже существует, независимо от её текущей версии
+const AnyVersion = -1
+
+// Функция проверки версии книги ордеров current перед условным сохранением;
+// отсутствующая книга имеет версию 0
+func versionMatches(current, expected int64) bool {
+	switch expected {
+	case 0:
+		return true
+	case AnyVersion:
+		return current > 0
+	default:
+		return current == expected
+	}
+}
 
 type Repository interface {
-	GetOrderBook(exchangeName, pair string) ([]*models.DepthOrder, error)
-	SaveOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error
-	GetOrderHistory(client *models.Client) ([]*models.HistoryOrder, error)
-	SaveOrder(client *models.Client, order *models.HistoryOrder) error
+	GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error)
+	SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error
+	// Возвращает книгу ордеров вместе с её версией и временем сохранения
+	GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error)
+	// Сохраняет книгу ордеров и возвращает её новый снимок. Если expectedVersion
+	// больше нуля, книга сохраняется, только если её текущая версия совпадает с
+	// ним; иначе, как и при отсутствии книги, возвращается ErrVersionMismatch.
+	// При AnyVersion книга сохраняется, только если она существует.
+	SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error)
+	GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error)
+	SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error
+	// Выполняет fn в одной транзакции: изменения, сделанные через tx, фиксируются
+	// вместе, если fn вернула nil, и откатываются, если fn вернула ошибку или
+	// вызвала панику. Ошибка fn возвращается без изменений.
+	WithTx(ctx context.Context, fn func(tx Repository) error) error
+}
+
+// Интерфейс хранилища, умеющего восстанавливать книги ордеров из архива.
+// Книга сохраняется с временем сохранения updatedAt, только если её нет
+// или сохранённая книга старше updatedAt; возвращает, была ли книга сохранена.
+type OrderBookRestorer interface {
+	RestoreOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, updatedAt time.Time) (bool, error)
 }
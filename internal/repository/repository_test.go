@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"StatisticsCollectionService/internal/migrations"
 	"StatisticsCollectionService/internal/models"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"testing"
@@ -18,49 +20,24 @@ func setupTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("Error connecting to test database: %v", err)
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS order_books (
-		id SERIAL PRIMARY KEY,
-		exchange VARCHAR(255) NOT NULL,
-		pair VARCHAR(255) NOT NULL,
-		asks JSONB NOT NULL,
-		bids JSONB NOT NULL,
-		UNIQUE (exchange, pair)
-	)`)
+	migrator, err := migrations.NewMigrator(db, migrations.Postgres)
 	if err != nil {
-		t.Fatalf("Error creating order_books table: %v", err)
+		t.Fatalf("Error loading migrations: %v", err)
 	}
-
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS order_history (
-		id SERIAL PRIMARY KEY,
-		client_name VARCHAR(255) NOT NULL,
-		exchange_name VARCHAR(255) NOT NULL,
-		label VARCHAR(255) NOT NULL,
-		pair VARCHAR(255) NOT NULL,
-		side VARCHAR(50) NOT NULL,
-		type VARCHAR(50) NOT NULL,
-		base_qty DOUBLE PRECISION NOT NULL,
-		price DOUBLE PRECISION NOT NULL,
-		algorithm_name_placed VARCHAR(255) NOT NULL,
-		lowest_sell_prc DOUBLE PRECISION NOT NULL,
-		highest_buy_prc DOUBLE PRECISION NOT NULL,
-		commission_quote_qty DOUBLE PRECISION NOT NULL,
-		time_placed TIMESTAMP NOT NULL
-	)`)
-	if err != nil {
-		t.Fatalf("Error creating order_history table: %v", err)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
 	}
 
 	return db
 }
 
 func teardownTestDB(t *testing.T, db *sql.DB) {
-	_, err := db.Exec(`DROP TABLE IF EXISTS order_books`)
+	migrator, err := migrations.NewMigrator(db, migrations.Postgres)
 	if err != nil {
-		t.Fatalf("Error dropping order_books table: %v", err)
+		t.Fatalf("Error loading migrations: %v", err)
 	}
-	_, err = db.Exec(`DROP TABLE IF EXISTS order_history`)
-	if err != nil {
-		t.Fatalf("Error dropping order_history table: %v", err)
+	if _, err := migrator.Down(context.Background(), migrator.Latest()); err != nil {
+		t.Fatalf("Error reverting migrations: %v", err)
 	}
 	db.Close()
 }
@@ -82,7 +59,7 @@ func TestPostgresRepository_GetOrderBook(t *testing.T) {
 	_, err := db.Exec(`INSERT INTO order_books (exchange, pair, asks, bids) VALUES ($1, $2, $3, $4)`, exchangeName, pair, asksJSON, bidsJSON)
 	assert.NoError(t, err)
 
-	orderBook, err := repo.GetOrderBook(exchangeName, pair)
+	orderBook, err := repo.GetOrderBook(context.Background(), exchangeName, pair)
 	assert.NoError(t, err)
 	assert.Len(t, orderBook, 2)
 	assert.Equal(t, asks[0], orderBook[0])
@@ -102,7 +79,7 @@ func TestPostgresRepository_SaveOrderBook(t *testing.T) {
 		{Price: 10500.0, BaseQty: 2.0},
 	}
 
-	err := repo.SaveOrderBook(exchangeName, pair, orderBook)
+	err := repo.SaveOrderBook(context.Background(), exchangeName, pair, orderBook)
 	assert.NoError(t, err)
 
 	var count int
@@ -151,7 +128,7 @@ func TestPostgresRepository_GetOrderHistory(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	history, err := repo.GetOrderHistory(client)
+	history, err := repo.GetOrderHistory(context.Background(), client)
 	assert.NoError(t, err)
 	assert.Len(t, history, 1)
 
@@ -184,7 +161,7 @@ func TestPostgresRepository_SaveOrder(t *testing.T) {
 		TimePlaced:          time.Now(),
 	}
 
-	err := repo.SaveOrder(client, order)
+	err := repo.SaveOrder(context.Background(), client, order)
 	assert.NoError(t, err)
 
 	var count int
@@ -0,0 +1,540 @@
+// Пакет repositorytest содержит набор тестов на соответствие, который должна
+// проходить любая реализация repository.Repository.
+package repositorytest
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Функция, создающая новый пустой репозиторий для очередного теста
+type Factory func(t *testing.T) repository.Repository
+
+// Функция запуска всех тестов на соответствие для репозитория
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.Repository)
+	}{
+		{"OrderBookRoundTrip", testOrderBookRoundTrip},
+		{"OrderBookOddLength", testOrderBookOddLength},
+		{"EmptyOrderBook", testEmptyOrderBook},
+		{"OrderBookOverwrite", testOrderBookOverwrite},
+		{"OrderBookKeys", testOrderBookKeys},
+		{"MissingOrderBook", testMissingOrderBook},
+		{"OrderBookVersions", testOrderBookVersions},
+		{"OrderBookVersionMismatch", testOrderBookVersionMismatch},
+		{"OrderHistoryRoundTrip", testOrderHistoryRoundTrip},
+		{"MissingOrderHistory", testMissingOrderHistory},
+		{"OrderHistoryPeriod", testOrderHistoryPeriod},
+		{"UnicodeNames", testUnicodeNames},
+		{"LargeHistory", testLargeHistory},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"TimeZoneRoundTrip", testTimeZoneRoundTrip},
+		{"CancelledContext", testCancelledContext},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+		{"TxPanic", testTxPanic},
+		{"TxNested", testTxNested},
+		{"TxCancelledContext", testTxCancelledContext},
+		{"TxConcurrentVersionedSave", testTxConcurrentVersionedSave},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func newOrder(clientName string, i int) *models.HistoryOrder {
+	return &models.HistoryOrder{
+		ClientName:          clientName,
+		ExchangeName:        "Binance",
+		Label:               fmt.Sprintf("order%d", i),
+		Pair:                "BTC/USDT",
+		Side:                "buy",
+		Type:                "limit",
+		BaseQty:             1.5,
+		Price:               10000.0 + float64(i),
+		AlgorithmNamePlaced: "alg1",
+		LowestSellPrice:     9900.0,
+		HighestBuyPrice:     10050.0,
+		CommissionQuoteQty:  0.1,
+		TimePlaced:          time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Second),
+	}
+}
+
+func testOrderBookRoundTrip(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	orderBook := []*models.DepthOrder{
+		{Price: 10000.0, BaseQty: 1.0},
+		{Price: 10001.5, BaseQty: 0.25},
+		{Price: 9999.0, BaseQty: 2.0},
+		{Price: 9998.5, BaseQty: 3.125},
+	}
+
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", orderBook))
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, orderBook, result)
+}
+
+func testOrderBookOddLength(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	orderBook := []*models.DepthOrder{
+		{Price: 1, BaseQty: 1},
+		{Price: 2, BaseQty: 2},
+		{Price: 3, BaseQty: 3},
+	}
+
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "ETH/USDT", orderBook))
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "ETH/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, orderBook, result)
+}
+
+func testEmptyOrderBook(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{}))
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func testOrderBookOverwrite(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{
+		{Price: 1, BaseQty: 1},
+		{Price: 2, BaseQty: 2},
+	}))
+	latest := []*models.DepthOrder{{Price: 3, BaseQty: 3}}
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", latest))
+
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, latest, result)
+}
+
+func testOrderBookKeys(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	books := map[[2]string][]*models.DepthOrder{
+		{"Binance", "BTC/USDT"}: {{Price: 1, BaseQty: 1}},
+		{"Binance", "ETH/USDT"}: {{Price: 2, BaseQty: 2}},
+		{"Kraken", "BTC/USDT"}:  {{Price: 3, BaseQty: 3}},
+	}
+	for key, book := range books {
+		require.NoError(t, repo.SaveOrderBook(ctx, key[0], key[1], book))
+	}
+
+	for key, book := range books {
+		result, err := repo.GetOrderBook(ctx, key[0], key[1])
+		require.NoError(t, err)
+		assert.Equal(t, book, result, "%s %s", key[0], key[1])
+	}
+}
+
+func testMissingOrderBook(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}))
+
+	for _, key := range [][2]string{{"Binance", "ETH/USDT"}, {"Kraken", "BTC/USDT"}, {"binance", "BTC/USDT"}, {"", ""}} {
+		_, err := repo.GetOrderBook(ctx, key[0], key[1])
+		assert.ErrorIs(t, err, repository.ErrNotFound, "%s %s", key[0], key[1])
+	}
+}
+
+func testOrderBookVersions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+
+	first, err := repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Version)
+	assert.True(t, first.UpdatedAt.After(before), "updated_at %v", first.UpdatedAt)
+
+	latest := []*models.DepthOrder{{Price: 2, BaseQty: 2}, {Price: 3, BaseQty: 3}}
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", latest))
+	_, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "ETH/USDT", latest, 0)
+	require.NoError(t, err)
+
+	snapshot, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, latest, snapshot.OrderBook)
+	assert.Equal(t, int64(2), snapshot.Version)
+	assert.False(t, snapshot.UpdatedAt.Before(first.UpdatedAt))
+	assert.Equal(t, time.UTC, snapshot.UpdatedAt.Location())
+
+	_, err = repo.GetOrderBookSnapshot(ctx, "Kraken", "BTC/USDT")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testOrderBookVersionMismatch(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	original := []*models.DepthOrder{{Price: 1, BaseQty: 1}}
+	_, err := repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", original, 0)
+	require.NoError(t, err)
+
+	_, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 2, BaseQty: 2}}, 2)
+	assert.ErrorIs(t, err, repository.ErrVersionMismatch)
+	_, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "ETH/USDT", original, 1)
+	assert.ErrorIs(t, err, repository.ErrVersionMismatch, "missing book must not be created")
+	_, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "ETH/USDT", original, repository.AnyVersion)
+	assert.ErrorIs(t, err, repository.ErrVersionMismatch, "missing book must not be created")
+
+	snapshot, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, original, snapshot.OrderBook)
+	assert.Equal(t, int64(1), snapshot.Version)
+	_, err = repo.GetOrderBook(ctx, "Binance", "ETH/USDT")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	latest := []*models.DepthOrder{{Price: 3, BaseQty: 3}}
+	saved, err := repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", latest, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), saved.Version)
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, latest, result)
+
+	saved, err = repo.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", original, repository.AnyVersion)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), saved.Version)
+}
+
+func testOrderHistoryRoundTrip(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+	order := newOrder("ignored", 1)
+
+	require.NoError(t, repo.SaveOrder(ctx, client, order))
+	require.NoError(t, repo.SaveOrder(ctx, &models.Client{ClientName: "Jane Doe"}, newOrder("Jane Doe", 2)))
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	expected := *order
+	expected.ClientName = client.ClientName
+	assert.Equal(t, &expected, history[0])
+}
+
+func testMissingOrderHistory(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, newOrder("John Doe", 1)))
+
+	for _, name := range []string{"Jane Doe", "john doe", ""} {
+		history, err := repo.GetOrderHistory(ctx, &models.Client{ClientName: name})
+		assert.NoError(t, err)
+		assert.Empty(t, history, name)
+	}
+}
+
+func testOrderHistoryPeriod(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+	months := []time.Month{time.January, time.February, time.March, time.April}
+	for i, month := range months {
+		order := newOrder(client.ClientName, i)
+		order.TimePlaced = time.Date(2024, month, 15, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.SaveOrder(ctx, client, order))
+	}
+
+	from := time.Date(2024, time.February, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.April, 1, 3, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	labels := func(c *models.Client) []string {
+		history, err := repo.GetOrderHistory(ctx, c)
+		require.NoError(t, err)
+		var result []string
+		for _, order := range history {
+			result = append(result, order.Label)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"order1", "order2"}, labels(&models.Client{ClientName: client.ClientName, TimeFrom: &from, TimeTo: &to}))
+	assert.Equal(t, []string{"order1", "order2", "order3"}, labels(&models.Client{ClientName: client.ClientName, TimeFrom: &from}))
+	assert.Equal(t, []string{"order0", "order1", "order2"}, labels(&models.Client{ClientName: client.ClientName, TimeTo: &to}))
+}
+
+func testUnicodeNames(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	names := []string{"Иван Петров", "客户一号", "Zoë 🚀", "O'Brien \"quoted\""}
+
+	for i, name := range names {
+		order := newOrder(name, i)
+		order.Label = name
+		order.AlgorithmNamePlaced = "алгоритм-" + name
+		require.NoError(t, repo.SaveOrder(ctx, &models.Client{ClientName: name}, order))
+	}
+	require.NoError(t, repo.SaveOrderBook(ctx, "Биржа", "РУБ/₮", []*models.DepthOrder{{Price: 1, BaseQty: 1}}))
+
+	for _, name := range names {
+		history, err := repo.GetOrderHistory(ctx, &models.Client{ClientName: name})
+		require.NoError(t, err)
+		require.Len(t, history, 1, name)
+		assert.Equal(t, name, history[0].ClientName)
+		assert.Equal(t, name, history[0].Label)
+		assert.Equal(t, "алгоритм-"+name, history[0].AlgorithmNamePlaced)
+	}
+
+	result, err := repo.GetOrderBook(ctx, "Биржа", "РУБ/₮")
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+}
+
+func testLargeHistory(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+	const count = 2000
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, repo.SaveOrder(ctx, client, newOrder(client.ClientName, i)))
+	}
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	require.Len(t, history, count)
+	for i, order := range history {
+		assert.Equal(t, fmt.Sprintf("order%d", i), order.Label, "orders must be returned in insertion order")
+	}
+}
+
+func testConcurrentWrites(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			client := &models.Client{ClientName: fmt.Sprintf("client%d", w)}
+			for i := 0; i < perWorker; i++ {
+				assert.NoError(t, repo.SaveOrder(ctx, client, newOrder(client.ClientName, i)))
+				assert.NoError(t, repo.SaveOrderBook(ctx, "Binance", fmt.Sprintf("PAIR%d", w), []*models.DepthOrder{{Price: float64(i), BaseQty: 1}}))
+				_, err := repo.GetOrderBook(ctx, "Binance", fmt.Sprintf("PAIR%d", w))
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		history, err := repo.GetOrderHistory(ctx, &models.Client{ClientName: fmt.Sprintf("client%d", w)})
+		require.NoError(t, err)
+		assert.Len(t, history, perWorker)
+
+		book, err := repo.GetOrderBook(ctx, "Binance", fmt.Sprintf("PAIR%d", w))
+		require.NoError(t, err)
+		assert.Equal(t, []*models.DepthOrder{{Price: perWorker - 1, BaseQty: 1}}, book)
+	}
+}
+
+func testTimeZoneRoundTrip(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	times := []time.Time{
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		time.Date(2024, time.July, 1, 2, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+		time.Date(2024, time.March, 10, 1, 59, 59, 0, newYork),
+		time.Date(2024, time.January, 1, 0, 0, 0, 123456789, time.FixedZone("", -9*60*60-30*60)),
+	}
+
+	client := &models.Client{ClientName: "John Doe"}
+	for i, ts := range times {
+		order := newOrder(client.ClientName, i)
+		order.TimePlaced = ts
+		require.NoError(t, repo.SaveOrder(ctx, client, order))
+	}
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	require.Len(t, history, len(times))
+	for i, ts := range times {
+		assert.WithinDuration(t, ts, history[i].TimePlaced, time.Microsecond, "instant must be preserved for %s", ts)
+		assert.Equal(t, time.UTC, history[i].TimePlaced.Location())
+	}
+}
+
+func testCancelledContext(t *testing.T, repo repository.Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	assert.ErrorIs(t, err, context.Canceled)
+	err = repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetOrderHistory(ctx, &models.Client{ClientName: "John Doe"})
+	assert.ErrorIs(t, err, context.Canceled)
+	err = repo.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, newOrder("John Doe", 1))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testTxCommit(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+	orderBook := []*models.DepthOrder{{Price: 1, BaseQty: 1}, {Price: 2, BaseQty: 2}}
+
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		if err := tx.SaveOrder(ctx, client, newOrder(client.ClientName, 1)); err != nil {
+			return err
+		}
+		if err := tx.SaveOrderBook(ctx, "Binance", "BTC/USDT", orderBook); err != nil {
+			return err
+		}
+
+		// Транзакция видит свои изменения, остальные — только после фиксации
+		history, err := tx.GetOrderHistory(ctx, client)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+		result, err := tx.GetOrderBook(ctx, "Binance", "BTC/USDT")
+		require.NoError(t, err)
+		assert.Equal(t, orderBook, result)
+
+		history, err = repo.GetOrderHistory(ctx, client)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+		_, err = repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		return nil
+	})
+	require.NoError(t, err)
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, orderBook, result)
+}
+
+func testTxRollback(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+	original := []*models.DepthOrder{{Price: 1, BaseQty: 1}}
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", original))
+	// Чтение заполняет кэш у кэширующих реализаций
+	_, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+
+	errAbort := errors.New("abort")
+	err = repo.WithTx(ctx, func(tx repository.Repository) error {
+		require.NoError(t, tx.SaveOrder(ctx, client, newOrder(client.ClientName, 1)))
+		require.NoError(t, tx.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 2, BaseQty: 2}}))
+		return errAbort
+	})
+	assert.Equal(t, errAbort, err, "the error of fn must be returned unchanged")
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	result, err := repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, original, result)
+}
+
+func testTxPanic(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		repo.WithTx(ctx, func(tx repository.Repository) error {
+			require.NoError(t, tx.SaveOrder(ctx, client, newOrder(client.ClientName, 1)))
+			panic("boom")
+		})
+	})
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// После отката репозиторий остаётся работоспособным
+	require.NoError(t, repo.SaveOrder(ctx, client, newOrder(client.ClientName, 2)))
+}
+
+func testTxNested(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	client := &models.Client{ClientName: "John Doe"}
+
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		require.NoError(t, tx.SaveOrder(ctx, client, newOrder(client.ClientName, 1)))
+		return tx.WithTx(ctx, func(inner repository.Repository) error {
+			history, err := inner.GetOrderHistory(ctx, client)
+			require.NoError(t, err)
+			assert.Len(t, history, 1, "nested transaction must see the outer one")
+			require.NoError(t, inner.SaveOrder(ctx, client, newOrder(client.ClientName, 2)))
+			return errors.New("abort")
+		})
+	})
+	require.Error(t, err)
+
+	history, err := repo.GetOrderHistory(ctx, client)
+	require.NoError(t, err)
+	assert.Empty(t, history, "failed nested transaction must roll back the outer one")
+}
+
+func testTxCancelledContext(t *testing.T, repo repository.Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, called)
+}
+
+// Условное сохранение в транзакции не должно затирать книгу, сохранённую
+// вне транзакции после проверки версии: либо другое сохранение выполняется
+// после фиксации, либо фиксация завершается ErrVersionMismatch
+func testTxConcurrentVersionedSave(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}}))
+	concurrent := []*models.DepthOrder{{Price: 3, BaseQty: 3}}
+
+	saved := make(chan error, 1)
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		if _, err := tx.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 2, BaseQty: 2}}, 1); err != nil {
+			return err
+		}
+		go func() { saved <- repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", concurrent) }()
+		// Базы данных блокируют другое сохранение до фиксации транзакции
+		select {
+		case err := <-saved:
+			saved <- err
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil
+	})
+	require.NoError(t, <-saved)
+
+	snapshot, getErr := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, getErr)
+	assert.Equal(t, concurrent, snapshot.OrderBook)
+	if err == nil {
+		assert.Equal(t, int64(3), snapshot.Version, "both saves must be applied")
+	} else {
+		assert.ErrorIs(t, err, repository.ErrVersionMismatch)
+		assert.Equal(t, int64(2), snapshot.Version)
+	}
+}
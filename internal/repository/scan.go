@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Колонки таблицы order_history в порядке, в котором их записывают репозитории
+var historyColumns = []string{
+	"client_name", "exchange_name", "label", "pair", "side", "type", "base_qty", "price",
+	"algorithm_name_placed", "lowest_sell_prc", "highest_buy_prc", "commission_quote_qty", "time_placed",
+}
+
+// Функция получения значений колонок historyColumns для ордера
+func historyValues(order *models.HistoryOrder) []interface{} {
+	return []interface{}{
+		order.ClientName,
+		order.ExchangeName,
+		order.Label,
+		order.Pair,
+		order.Side,
+		order.Type,
+		order.BaseQty,
+		order.Price,
+		order.AlgorithmNamePlaced,
+		order.LowestSellPrice,
+		order.HighestBuyPrice,
+		order.CommissionQuoteQty,
+		normalizeTime(order.TimePlaced),
+	}
+}
+
+// Запрос вставки одного ордера в order_history
+var insertHistoryQuery = "INSERT INTO order_history (" + strings.Join(historyColumns, ", ") +
+	") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)"
+
+// Функция построения запроса истории ордеров клиента. Границы периода
+// позволяют PostgreSQL читать только секции order_history за этот период.
+func historyQuery(client *models.Client) (string, []interface{}) {
+	query := "SELECT " + strings.Join(historyColumns, ", ") + " FROM order_history WHERE client_name = $1"
+	args := []interface{}{client.ClientName}
+	if client.TimeFrom != nil {
+		args = append(args, normalizeTime(*client.TimeFrom))
+		query += fmt.Sprintf(" AND time_placed >= $%d", len(args))
+	}
+	if client.TimeTo != nil {
+		args = append(args, normalizeTime(*client.TimeTo))
+		query += fmt.Sprintf(" AND time_placed < $%d", len(args))
+	}
+	return query + " ORDER BY id", args
+}
+
+// Функция проверки, что ордер попадает в период, запрошенный клиентом
+func inHistoryPeriod(client *models.Client, order *models.HistoryOrder) bool {
+	if client.TimeFrom != nil && order.TimePlaced.Before(normalizeTime(*client.TimeFrom)) {
+		return false
+	}
+	if client.TimeTo != nil && !order.TimePlaced.Before(normalizeTime(*client.TimeTo)) {
+		return false
+	}
+	return true
+}
+
+// Функция чтения ордера из строки результата запроса истории
+func scanHistoryOrder(rows resultRows) (*models.HistoryOrder, error) {
+	var order models.HistoryOrder
+	err := rows.Scan(
+		&order.ClientName,
+		&order.ExchangeName,
+		&order.Label,
+		&order.Pair,
+		&order.Side,
+		&order.Type,
+		&order.BaseQty,
+		&order.Price,
+		&order.AlgorithmNamePlaced,
+		&order.LowestSellPrice,
+		&order.HighestBuyPrice,
+		&order.CommissionQuoteQty,
+		&order.TimePlaced,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Функция разбора asks и bids книги ордеров из JSON
+func unmarshalOrderBook(asksJSON, bidsJSON []byte) ([]*models.DepthOrder, error) {
+	var asks, bids []*models.DepthOrder
+	if err := json.Unmarshal(asksJSON, &asks); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bidsJSON, &bids); err != nil {
+		return nil, err
+	}
+	return append(asks, bids...), nil
+}
+
+// Функция сериализации книги ордеров: первая половина — asks, вторая — bids
+func marshalOrderBook(orderBook []*models.DepthOrder) (asksJSON, bidsJSON []byte, err error) {
+	asksJSON, err = json.Marshal(orderBook[:len(orderBook)/2])
+	if err != nil {
+		return nil, nil, err
+	}
+	bidsJSON, err = json.Marshal(orderBook[len(orderBook)/2:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return asksJSON, bidsJSON, nil
+}
+
+// Функция приведения времени к точности и часовому поясу, в которых его хранит PostgreSQL
+func normalizeTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond).UTC()
+}
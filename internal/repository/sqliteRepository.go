@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Структура репозитория для работы со встроенной базой SQLite
+type SQLiteRepository struct {
+	db querier
+	// Пул соединений для начала транзакций; nil внутри транзакции
+	conn *sql.DB
+}
+
+// Конструктор для создания нового репозитория SQLite
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: traced(db, semconv.DBSystemSqlite), conn: db}
+}
+
+// Метод для получения книги ордеров из базы данных
+func (r *SQLiteRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	snapshot, err := r.GetOrderBookSnapshot(ctx, exchangeName, pair)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.OrderBook, nil
+}
+
+// Метод для получения книги ордеров с версией и временем сохранения
+func (r *SQLiteRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	query := `SELECT asks, bids, version, updated_at FROM order_books WHERE exchange = $1 AND pair = $2`
+
+	var snapshot models.OrderBookSnapshot
+	var asksJSON, bidsJSON []byte
+	row := r.db.QueryRowContext(ctx, query, exchangeName, pair)
+	if err := row.Scan(&asksJSON, &bidsJSON, &snapshot.Version, &snapshot.UpdatedAt); err != nil {
+		return nil, sqliteError(err)
+	}
+
+	orderBook, err := unmarshalOrderBook(asksJSON, bidsJSON)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.OrderBook = orderBook
+	snapshot.UpdatedAt = snapshot.UpdatedAt.UTC()
+	return &snapshot, nil
+}
+
+// Метод для сохранения книги ордеров в базе данных
+func (r *SQLiteRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	_, err := r.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, 0)
+	return err
+}
+
+// Метод для сохранения книги ордеров с увеличением её версии. При
+// expectedVersion больше нуля книга обновляется, только если её версия не
+// изменилась с момента чтения, при AnyVersion — только если книга существует.
+func (r *SQLiteRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	asksJSON, bidsJSON, err := marshalOrderBook(orderBook)
+	if err != nil {
+		return nil, wrapError(ErrInvalid, err)
+	}
+	snapshot := &models.OrderBookSnapshot{OrderBook: orderBook, UpdatedAt: normalizeTime(time.Now())}
+	if expectedVersion != 0 {
+		query := `UPDATE order_books SET asks = $3, bids = $4, updated_at = $5, version = version + 1 WHERE exchange = $1 AND pair = $2`
+		args := []interface{}{exchangeName, pair, string(asksJSON), string(bidsJSON), snapshot.UpdatedAt}
+		if expectedVersion != AnyVersion {
+			query += ` AND version = $6`
+			args = append(args, expectedVersion)
+		}
+		err = r.db.QueryRowContext(ctx, query+` RETURNING version`, args...).Scan(&snapshot.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionMismatch
+		}
+	} else {
+		query := `INSERT INTO order_books (exchange, pair, asks, bids, updated_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (exchange, pair) DO UPDATE SET asks = excluded.asks, bids = excluded.bids, updated_at = excluded.updated_at, version = order_books.version + 1 RETURNING version`
+		err = r.db.QueryRowContext(ctx, query, exchangeName, pair, string(asksJSON), string(bidsJSON), snapshot.UpdatedAt).Scan(&snapshot.Version)
+	}
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	return snapshot, nil
+}
+
+// Метод для восстановления книги ордеров из архива с исходным временем
+// сохранения. Более новая книга, сохранённая после архивации, не заменяется.
+func (r *SQLiteRepository) RestoreOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, updatedAt time.Time) (bool, error) {
+	asksJSON, bidsJSON, err := marshalOrderBook(orderBook)
+	if err != nil {
+		return false, wrapError(ErrInvalid, err)
+	}
+	query := `INSERT INTO order_books (exchange, pair, asks, bids, updated_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (exchange, pair) DO UPDATE SET asks = excluded.asks, bids = excluded.bids, updated_at = excluded.updated_at, version = order_books.version + 1 WHERE order_books.updated_at < excluded.updated_at`
+	result, err := r.db.ExecContext(ctx, query, exchangeName, pair, string(asksJSON), string(bidsJSON), normalizeTime(updatedAt))
+	if err != nil {
+		return false, sqliteError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, sqliteError(err)
+	}
+	return n > 0, nil
+}
+
+// Метод для получения истории ордеров из базы данных в порядке сохранения
+func (r *SQLiteRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	query, args := historyQuery(client)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	defer rows.Close()
+
+	var orders []*models.HistoryOrder
+	for rows.Next() {
+		order, err := scanHistoryOrder(rows)
+		if err != nil {
+			return nil, sqliteError(err)
+		}
+		order.TimePlaced = order.TimePlaced.UTC()
+		orders = append(orders, order)
+	}
+	return orders, sqliteError(rows.Err())
+}
+
+// Метод для сохранения ордера в базе данных
+func (r *SQLiteRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	query := `INSERT INTO order_history (client_name, exchange_name, label, pair, side, type, base_qty, price, algorithm_name_placed, lowest_sell_prc, highest_buy_prc, commission_quote_qty, time_placed) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	_, err := r.db.ExecContext(ctx, query,
+		client.ClientName,
+		order.ExchangeName,
+		order.Label,
+		order.Pair,
+		order.Side,
+		order.Type,
+		order.BaseQty,
+		order.Price,
+		order.AlgorithmNamePlaced,
+		order.LowestSellPrice,
+		order.HighestBuyPrice,
+		order.CommissionQuoteQty,
+		normalizeTime(order.TimePlaced),
+	)
+	return sqliteError(err)
+}
+
+// Метод для пакетного сохранения ордеров одной транзакцией. Драйвер SQLite
+// медленно связывает большое число параметров, поэтому вместо многострочного
+// INSERT ордера вставляются по одному внутри общей транзакции.
+func (r *SQLiteRepository) SaveOrders(ctx context.Context, orders []*models.HistoryOrder) error {
+	return r.withTx(ctx, func(tx *SQLiteRepository) error {
+		stmt, err := tx.db.PrepareContext(ctx, insertHistoryQuery)
+		if err != nil {
+			return sqliteError(err)
+		}
+		defer stmt.Close()
+
+		for _, order := range orders {
+			if _, err := stmt.ExecContext(ctx, historyValues(order)...); err != nil {
+				return sqliteError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// Метод для выполнения нескольких операций в одной транзакции. Репозиторий,
+// переданный в fn, выполняет запросы в транзакции; вложенный вызов WithTx
+// использует уже открытую транзакцию.
+func (r *SQLiteRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return r.withTx(ctx, func(tx *SQLiteRepository) error { return fn(tx) })
+}
+
+func (r *SQLiteRepository) withTx(ctx context.Context, fn func(tx *SQLiteRepository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+	return runTx(ctx, r.conn, sqliteError, func(tx *sql.Tx) error {
+		return fn(&SQLiteRepository{db: traced(tx, semconv.DBSystemSqlite)})
+	})
+}
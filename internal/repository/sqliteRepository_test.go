@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/migrations"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupSQLiteDB(t testing.TB) *sql.DB {
+	cfg := config.StorageConfig{
+		SQLitePath:        filepath.Join(t.TempDir(), "stats.db"),
+		SQLiteBusyTimeout: 5 * time.Second,
+	}
+	sqliteDB, err := sql.Open("sqlite", db.SQLiteDSN(cfg))
+	if err != nil {
+		t.Fatalf("Error opening test database: %v", err)
+	}
+	t.Cleanup(func() { sqliteDB.Close() })
+
+	migrator, err := migrations.NewMigrator(sqliteDB, migrations.SQLite)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+	return sqliteDB
+}
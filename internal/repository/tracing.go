@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Трассировщик SQL-запросов. Использует глобальный TracerProvider,
+// поэтому без настроенной трассировки spans не создаются.
+var tracer = otel.Tracer("StatisticsCollectionService/internal/repository")
+
+// Обёртка над подключением или транзакцией, создающая span на каждый SQL-запрос
+type tracedQuerier struct {
+	q      sqlQuerier
+	system attribute.KeyValue
+}
+
+// Функция оборачивания подключения или транзакции в трассировку запросов
+func traced(q sqlQuerier, system attribute.KeyValue) querier {
+	return tracedQuerier{q: q, system: system}
+}
+
+// Метод начала span запроса; имя span — SQL-операция (SELECT, INSERT, ...)
+func (t tracedQuerier) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.system, semconv.DBOperationName(operation), semconv.DBQueryText(query)),
+	)
+}
+
+// Функция завершения span с отметкой об ошибке
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	result, err := t.q.ExecContext(ctx, query, args...)
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", n))
+		}
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// Метод выполнения запроса. Span завершается не сразу, а после чтения всех
+// строк или закрытия результата, чтобы учитывать время передачи данных.
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (resultRows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// Строки результата запроса, завершающие span запроса, когда строки
+// закончились или результат закрыт
+type tracedRows struct {
+	*sql.Rows
+	span  trace.Span
+	count int64
+	once  sync.Once
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.end()
+	return err
+}
+
+func (r *tracedRows) end() {
+	r.once.Do(func() {
+		r.span.SetAttributes(attribute.Int64("db.rows_returned", r.count))
+		endSpan(r.span, r.Rows.Err())
+	})
+}
+
+// Метод выполнения запроса одной строки. Span завершается после чтения
+// строки методом Scan.
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) resultRow {
+	ctx, span := t.start(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		endSpan(span, err)
+		return row
+	}
+	return &tracedRow{Row: row, span: span}
+}
+
+// Строка результата запроса, завершающая span запроса после чтения
+type tracedRow struct {
+	*sql.Row
+	span trace.Span
+	once sync.Once
+}
+
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.once.Do(func() { endSpan(r.span, err) })
+	return err
+}
+
+func (t tracedQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	stmt, err := t.q.PrepareContext(ctx, query)
+	endSpan(span, err)
+	return stmt, err
+}
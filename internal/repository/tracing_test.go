@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Span запроса должен охватывать чтение строк, а не только ожидание первого ответа
+func TestTracedRows(t *testing.T) {
+	db := setupSQLiteDB(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	query := func() *tracedRows {
+		_, span := provider.Tracer("test").Start(context.Background(), "SELECT")
+		rows, err := db.QueryContext(context.Background(), "SELECT 1 UNION ALL SELECT 2")
+		require.NoError(t, err)
+		return &tracedRows{Rows: rows, span: span}
+	}
+
+	rows := query()
+	for rows.Next() {
+		assert.Empty(t, recorder.Ended(), "span ended before the rows were read")
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.NoError(t, rows.Close())
+	require.Len(t, recorder.Ended(), 1)
+	span := recorder.Ended()[0]
+	assert.GreaterOrEqual(t, span.EndTime().Sub(span.StartTime()), 40*time.Millisecond)
+	assert.Contains(t, span.Attributes(), attribute.Int64("db.rows_returned", 2))
+
+	// Закрытие до окончания строк тоже завершает span, причём один раз
+	rows = query()
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+	require.NoError(t, rows.Close())
+	require.Len(t, recorder.Ended(), 2)
+	assert.Contains(t, recorder.Ended()[1].Attributes(), attribute.Int64("db.rows_returned", 1))
+}
+
+// Span запроса одной строки завершается после чтения строки
+func TestTracedRow(t *testing.T) {
+	db := setupSQLiteDB(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	_, span := provider.Tracer("test").Start(context.Background(), "SELECT")
+	row := &tracedRow{Row: db.QueryRowContext(context.Background(), "SELECT 1"), span: span}
+	assert.Empty(t, recorder.Ended(), "span ended before the row was read")
+
+	var n int
+	require.NoError(t, row.Scan(&n))
+	assert.Equal(t, 1, n)
+	require.Len(t, recorder.Ended(), 1)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Общий интерфейс *sql.DB и *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Интерфейс, через который SQL-репозитории выполняют запросы. Отличается от
+// sqlQuerier результатами QueryContext и QueryRowContext, чтобы строки можно
+// было читать через обёртку.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (resultRows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) resultRow
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Строки результата запроса: *sql.Rows или его обёртка
+type resultRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// Строка результата запроса: *sql.Row или его обёртка
+type resultRow interface {
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// Функция выполнения fn в транзакции базы данных. Транзакция фиксируется, если fn
+// завершилась без ошибки, и откатывается при ошибке или панике. Ошибка fn
+// возвращается без изменений, ошибки начала и фиксации транзакции — через convert.
+func runTx(ctx context.Context, conn *sql.DB, convert func(error) error, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return convert(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = convert(tx.Commit())
+	}()
+	return fn(tx)
+}
@@ -0,0 +1,64 @@
+// Пакет requestid присваивает каждому HTTP-запросу идентификатор и передаёт его через контекст.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Заголовок, в котором передаётся идентификатор запроса
+const Header = "X-Request-ID"
+
+// Максимальная длина идентификатора, принимаемого от клиента
+const maxLength = 128
+
+type contextKey struct{}
+
+// Функция для сохранения идентификатора запроса в контексте
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Функция для получения идентификатора запроса из контекста
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Функция генерации нового идентификатора запроса
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Middleware, которое берёт идентификатор из заголовка X-Request-ID или создаёт новый,
+// возвращает его в ответе и сохраняет в контексте запроса
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Функция проверки идентификатора, полученного от клиента: допускаются только
+// печатные ASCII-символы, чтобы его можно было безопасно записывать в логи и заголовки
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		header   string
+		preserve bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"with space", false},
+		{strings.Repeat("a", maxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set(Header, tt.header)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.NotEmpty(t, seen)
+		assert.Equal(t, seen, rr.Header().Get(Header))
+		if tt.preserve {
+			assert.Equal(t, tt.header, seen)
+		} else {
+			assert.NotEqual(t, tt.header, seen)
+			assert.Len(t, seen, 32)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package retention
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Типы данных в архивах
+const (
+	KindOrderBooks   = "order_books"
+	KindOrderHistory = "order_history"
+)
+
+// Версия формата архива
+const archiveVersion = 1
+
+// Заголовок архива — первая строка файла
+type archiveHeader struct {
+	Kind      string    `json:"kind"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Records   int       `json:"records"`
+}
+
+// Запись архива книг ордеров
+type orderBookRecord struct {
+	Exchange  string               `json:"exchange"`
+	Pair      string               `json:"pair"`
+	OrderBook []*models.DepthOrder `json:"order_book"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// Функция записи архива: gzip-сжатый JSON Lines, первая строка — заголовок.
+// Файл сначала пишется во временный и переименовывается только после
+// сброса на диск, поэтому архив с итоговым именем всегда полный.
+func writeArchive(dir, name string, header archiveHeader, records []interface{}) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(gz)
+	header.Version = archiveVersion
+	header.Records = len(records)
+	if err := enc.Encode(header); err != nil {
+		return "", err
+	}
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Функция повторного импорта архива в репозиторий. Все записи файла
+// сохраняются в одной транзакции; возвращает число импортированных записей.
+// Книги ордеров восстанавливаются с исходным временем сохранения и только
+// если в репозитории нет более новой книги; пропущенные книги не входят
+// в число импортированных. Для этого репозиторий должен реализовывать
+// repository.OrderBookRestorer.
+func Import(ctx context.Context, r io.Reader, repo repository.Repository) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("open archive: %w", err)
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	var header archiveHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("read archive header: %w", err)
+	}
+	if header.Version != archiveVersion {
+		return 0, fmt.Errorf("unsupported archive version %d", header.Version)
+	}
+
+	read, imported := 0, 0
+	err = repo.WithTx(ctx, func(tx repository.Repository) error {
+		restorer, _ := tx.(repository.OrderBookRestorer)
+		if header.Kind == KindOrderBooks && restorer == nil {
+			return errors.New("the repository cannot restore order books")
+		}
+		for {
+			var err error
+			saved := true
+			switch header.Kind {
+			case KindOrderBooks:
+				var record orderBookRecord
+				if err = dec.Decode(&record); err == nil {
+					saved, err = restorer.RestoreOrderBook(ctx, record.Exchange, record.Pair, record.OrderBook, record.UpdatedAt)
+				}
+			case KindOrderHistory:
+				var order models.HistoryOrder
+				if err = dec.Decode(&order); err == nil {
+					err = tx.SaveOrder(ctx, &models.Client{ClientName: order.ClientName}, &order)
+				}
+			default:
+				return fmt.Errorf("unknown archive kind %q", header.Kind)
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("import record %d: %w", read+1, err)
+			}
+			read++
+			if saved {
+				imported++
+			}
+		}
+		if read != header.Records {
+			return fmt.Errorf("archive is truncated: %d of %d records", read, header.Records)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,281 @@
+// Пакет retention применяет политику хранения данных: записи старше срока
+// хранения выгружаются в сжатые архивы на локальном диске и удаляются из базы.
+// Архивы можно загрузить обратно функцией Import.
+package retention
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Политика хранения данных. Нулевой срок означает бессрочное хранение.
+type Policy struct {
+	OrderBooks   time.Duration
+	OrderHistory time.Duration
+	// Сроки хранения истории ордеров отдельных клиентов
+	Clients map[string]time.Duration
+}
+
+// Функция разбора переопределений срока хранения вида "клиент=срок"
+func ParseClientOverrides(entries []string) (map[string]time.Duration, error) {
+	overrides := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("client override %q must have the form client=duration", entry)
+		}
+		d, err := time.ParseDuration(entry[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("client override %q: %w", entry, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("client override %q must not be negative", entry)
+		}
+		overrides[entry[:i]] = d
+	}
+	return overrides, nil
+}
+
+// Результат выгрузки устаревших данных
+type Result struct {
+	OrderBooks   int
+	OrderHistory int
+	Files        []string
+}
+
+// Структура для выгрузки устаревших данных в архивы
+type Archiver struct {
+	db        *sql.DB
+	policy    Policy
+	dir       string
+	batchSize int
+	now       func() time.Time
+}
+
+// Конструктор для создания архиватора. Архивы пишутся в каталог dir,
+// за один проход выгружается не более batchSize записей в файл.
+func NewArchiver(db *sql.DB, policy Policy, dir string, batchSize int) *Archiver {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &Archiver{db: db, policy: policy, dir: dir, batchSize: batchSize, now: time.Now}
+}
+
+// Метод для выгрузки в архивы и удаления всех данных старше срока хранения
+func (a *Archiver) Archive(ctx context.Context) (Result, error) {
+	var result Result
+	now := a.now().UTC()
+	stamp := now.Format("20060102T150405Z")
+
+	if a.policy.OrderBooks > 0 {
+		if err := a.archiveOrderBooks(ctx, now.Add(-a.policy.OrderBooks), stamp, &result); err != nil {
+			return result, err
+		}
+	}
+
+	// Клиенты с собственным сроком обрабатываются отдельно от общего правила
+	clients := make([]string, 0, len(a.policy.Clients))
+	for client := range a.policy.Clients {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	if a.policy.OrderHistory > 0 {
+		filter := historyFilter{cutoff: now.Add(-a.policy.OrderHistory), exclude: clients}
+		if err := a.archiveHistory(ctx, filter, stamp, &result); err != nil {
+			return result, err
+		}
+	}
+	for _, client := range clients {
+		if retention := a.policy.Clients[client]; retention > 0 {
+			filter := historyFilter{cutoff: now.Add(-retention), client: client}
+			if err := a.archiveHistory(ctx, filter, stamp, &result); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// Метод для периодической выгрузки устаревших данных до отмены контекста
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := a.Archive(ctx)
+		if result.OrderBooks > 0 || result.OrderHistory > 0 {
+			slog.Info("Archived expired data", "order_books", result.OrderBooks, "orders", result.OrderHistory, "files", len(result.Files))
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Error archiving expired data", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Archiver) archiveOrderBooks(ctx context.Context, cutoff time.Time, stamp string, result *Result) error {
+	for {
+		query := `SELECT id, exchange, pair, asks, bids, updated_at FROM order_books WHERE updated_at < $1 ORDER BY id LIMIT $2`
+		rows, err := a.db.QueryContext(ctx, query, cutoff, a.batchSize)
+		if err != nil {
+			return fmt.Errorf("select expired order books: %w", err)
+		}
+
+		var ids []int64
+		var records []interface{}
+		for rows.Next() {
+			var id int64
+			var record orderBookRecord
+			var asksJSON, bidsJSON []byte
+			if err := rows.Scan(&id, &record.Exchange, &record.Pair, &asksJSON, &bidsJSON, &record.UpdatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("select expired order books: %w", err)
+			}
+			var asks, bids []*models.DepthOrder
+			if err := json.Unmarshal(asksJSON, &asks); err != nil {
+				rows.Close()
+				return fmt.Errorf("order book %d: %w", id, err)
+			}
+			if err := json.Unmarshal(bidsJSON, &bids); err != nil {
+				rows.Close()
+				return fmt.Errorf("order book %d: %w", id, err)
+			}
+			record.OrderBook = append(asks, bids...)
+			record.UpdatedAt = record.UpdatedAt.UTC()
+			ids = append(ids, id)
+			records = append(records, record)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("select expired order books: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		name := fmt.Sprintf("%s-%s-%04d.jsonl.gz", KindOrderBooks, stamp, len(result.Files)+1)
+		path, err := writeArchive(a.dir, name, archiveHeader{Kind: KindOrderBooks, CreatedAt: a.now().UTC()}, records)
+		if err != nil {
+			return fmt.Errorf("write archive %s: %w", name, err)
+		}
+		result.Files = append(result.Files, path)
+
+		// Книга, сохранённая заново после выборки, не удаляется благодаря условию на updated_at
+		if err := a.delete(ctx, "order_books", "updated_at", cutoff, ids); err != nil {
+			return err
+		}
+		result.OrderBooks += len(ids)
+	}
+}
+
+// Условие выборки устаревших ордеров: либо ордера одного клиента,
+// либо ордера всех клиентов, кроме перечисленных
+type historyFilter struct {
+	cutoff  time.Time
+	client  string
+	exclude []string
+}
+
+func (f historyFilter) where() (string, []interface{}) {
+	where := "time_placed < $1"
+	args := []interface{}{f.cutoff}
+	if f.client != "" {
+		args = append(args, f.client)
+		return where + fmt.Sprintf(" AND client_name = $%d", len(args)), args
+	}
+	if len(f.exclude) > 0 {
+		placeholders := make([]string, len(f.exclude))
+		for i, client := range f.exclude {
+			args = append(args, client)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		where += " AND client_name NOT IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	return where, args
+}
+
+func (a *Archiver) archiveHistory(ctx context.Context, filter historyFilter, stamp string, result *Result) error {
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT id, client_name, exchange_name, label, pair, side, type, base_qty, price, algorithm_name_placed, lowest_sell_prc, highest_buy_prc, commission_quote_qty, time_placed FROM order_history WHERE %s ORDER BY id LIMIT $%d`, where, len(args)+1)
+	args = append(args, a.batchSize)
+
+	for {
+		rows, err := a.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("select expired orders: %w", err)
+		}
+
+		var ids []int64
+		var records []interface{}
+		for rows.Next() {
+			var id int64
+			var order models.HistoryOrder
+			err := rows.Scan(&id, &order.ClientName, &order.ExchangeName, &order.Label, &order.Pair, &order.Side, &order.Type,
+				&order.BaseQty, &order.Price, &order.AlgorithmNamePlaced, &order.LowestSellPrice, &order.HighestBuyPrice,
+				&order.CommissionQuoteQty, &order.TimePlaced)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("select expired orders: %w", err)
+			}
+			order.TimePlaced = order.TimePlaced.UTC()
+			ids = append(ids, id)
+			records = append(records, order)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("select expired orders: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		name := fmt.Sprintf("%s-%s-%04d.jsonl.gz", KindOrderHistory, stamp, len(result.Files)+1)
+		path, err := writeArchive(a.dir, name, archiveHeader{Kind: KindOrderHistory, CreatedAt: a.now().UTC()}, records)
+		if err != nil {
+			return fmt.Errorf("write archive %s: %w", name, err)
+		}
+		result.Files = append(result.Files, path)
+
+		// Условие на time_placed позволяет PostgreSQL удалять только из нужных секций
+		if err := a.delete(ctx, "order_history", "time_placed", filter.cutoff, ids); err != nil {
+			return err
+		}
+		result.OrderHistory += len(ids)
+	}
+}
+
+// Метод удаления выгруженных строк одной транзакцией
+func (a *Archiver) delete(ctx context.Context, table, timeColumn string, cutoff time.Time, ids []int64) error {
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, cutoff)
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s < $1 AND id IN (%s)`, table, timeColumn, strings.Join(placeholders, ", "))
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete archived %s: %w", table, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("delete archived %s: %w", table, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete archived %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package retention
+
+import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/migrations"
+	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func setupSQLiteDB(t *testing.T) *sql.DB {
+	cfg := config.StorageConfig{SQLitePath: filepath.Join(t.TempDir(), "stats.db"), SQLiteBusyTimeout: 5 * time.Second}
+	sqliteDB, err := sql.Open("sqlite", db.SQLiteDSN(cfg))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDB.Close() })
+
+	migrator, err := migrations.NewMigrator(sqliteDB, migrations.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return sqliteDB
+}
+
+func saveOrder(t *testing.T, repo repository.Repository, client, label string, placed time.Time) {
+	order := &models.HistoryOrder{ExchangeName: "Binance", Pair: "BTC/USDT", Label: label, TimePlaced: placed}
+	require.NoError(t, repo.SaveOrder(context.Background(), &models.Client{ClientName: client}, order))
+}
+
+func labels(t *testing.T, repo repository.Repository, client string) []string {
+	history, err := repo.GetOrderHistory(context.Background(), &models.Client{ClientName: client})
+	require.NoError(t, err)
+	var result []string
+	for _, order := range history {
+		result = append(result, order.Label)
+	}
+	return result
+}
+
+func TestParseClientOverrides(t *testing.T) {
+	overrides, err := ParseClientOverrides([]string{"John Doe=720h", "a=b=1h"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"John Doe": 720 * time.Hour, "a=b": time.Hour}, overrides)
+
+	for _, entry := range []string{"John Doe", "=1h", "John Doe=forever", "John Doe=-1h"} {
+		_, err := ParseClientOverrides([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestArchiver_ArchiveAndImport(t *testing.T) {
+	ctx := context.Background()
+	sqliteDB := setupSQLiteDB(t)
+	repo := repository.NewSQLiteRepository(sqliteDB)
+	now := time.Now().UTC()
+
+	saveOrder(t, repo, "John Doe", "old", now.Add(-48*time.Hour))
+	saveOrder(t, repo, "John Doe", "new", now.Add(-time.Hour))
+	saveOrder(t, repo, "VIP", "old", now.Add(-48*time.Hour))
+	saveOrder(t, repo, "Short", "recent", now.Add(-2*time.Hour))
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", []*models.DepthOrder{{Price: 1, BaseQty: 1}, {Price: 2, BaseQty: 2}}))
+	require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "ETH/USDT", []*models.DepthOrder{{Price: 3, BaseQty: 3}}))
+	_, err := sqliteDB.Exec(`UPDATE order_books SET updated_at = $1 WHERE pair = 'BTC/USDT'`, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	policy := Policy{
+		OrderBooks:   24 * time.Hour,
+		OrderHistory: 24 * time.Hour,
+		Clients:      map[string]time.Duration{"VIP": 0, "Short": time.Hour},
+	}
+	archiver := NewArchiver(sqliteDB, policy, dir, 1)
+	result, err := archiver.Archive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.OrderBooks)
+	assert.Equal(t, 2, result.OrderHistory)
+	assert.Len(t, result.Files, 3, "batch size 1 produces a file per record")
+
+	// Данные старше срока удалены, клиент с бессрочным хранением не затронут
+	assert.Equal(t, []string{"new"}, labels(t, repo, "John Doe"))
+	assert.Equal(t, []string{"old"}, labels(t, repo, "VIP"))
+	assert.Empty(t, labels(t, repo, "Short"))
+	_, err = repo.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetOrderBook(ctx, "Binance", "ETH/USDT")
+	assert.NoError(t, err)
+
+	// Повторный запуск ничего не находит
+	result, err = archiver.Archive(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Files)
+
+	restored := repository.NewInMemoryRepository()
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	for _, path := range files {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		n, err := Import(ctx, f, restored)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	assert.Equal(t, []string{"old"}, labels(t, restored, "John Doe"))
+	assert.Equal(t, []string{"recent"}, labels(t, restored, "Short"))
+	book, err := restored.GetOrderBook(ctx, "Binance", "BTC/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, []*models.DepthOrder{{Price: 1, BaseQty: 1}, {Price: 2, BaseQty: 2}}, book)
+
+	history, err := restored.GetOrderHistory(ctx, &models.Client{ClientName: "John Doe"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-48*time.Hour), history[0].TimePlaced, time.Microsecond)
+}
+
+func TestImport_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	require.NoError(t, enc.Encode(archiveHeader{Kind: KindOrderHistory, Version: archiveVersion, Records: 2}))
+	require.NoError(t, enc.Encode(models.HistoryOrder{ClientName: "John Doe", Label: "first"}))
+	require.NoError(t, gz.Close())
+
+	repo := repository.NewInMemoryRepository()
+	_, err := Import(context.Background(), &buf, repo)
+	assert.ErrorContains(t, err, "truncated")
+	assert.Empty(t, labels(t, repo, "John Doe"), "a failed import must not leave partial data")
+}
+
+// Архивная книга не заменяет более новую книгу и сохраняет исходное время сохранения
+func TestImport_OrderBooks(t *testing.T) {
+	ctx := context.Background()
+	archived := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Microsecond)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	require.NoError(t, enc.Encode(archiveHeader{Kind: KindOrderBooks, Version: archiveVersion, Records: 3}))
+	for _, pair := range []string{"BTC/USDT", "ETH/USDT", "SOL/USDT"} {
+		require.NoError(t, enc.Encode(orderBookRecord{Exchange: "Binance", Pair: pair, OrderBook: []*models.DepthOrder{{Price: 1, BaseQty: 1}}, UpdatedAt: archived}))
+	}
+	require.NoError(t, gz.Close())
+
+	for name, repo := range map[string]repository.Repository{
+		"memory": repository.NewInMemoryRepository(),
+		"sqlite": repository.NewSQLiteRepository(setupSQLiteDB(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			live := []*models.DepthOrder{{Price: 2, BaseQty: 2}}
+			require.NoError(t, repo.SaveOrderBook(ctx, "Binance", "BTC/USDT", live))
+			before, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USDT")
+			require.NoError(t, err)
+			restorer := repo.(repository.OrderBookRestorer)
+			restored, err := restorer.RestoreOrderBook(ctx, "Binance", "ETH/USDT", live, archived.Add(-time.Hour))
+			require.NoError(t, err)
+			require.True(t, restored)
+
+			n, err := Import(ctx, bytes.NewReader(buf.Bytes()), repo)
+			require.NoError(t, err)
+			assert.Equal(t, 2, n, "the newer live book is skipped")
+
+			// Более новая книга не изменилась
+			after, err := repo.GetOrderBookSnapshot(ctx, "Binance", "BTC/USDT")
+			require.NoError(t, err)
+			assert.Equal(t, before, after)
+
+			// Более старая книга заменена архивной, отсутствующая восстановлена
+			for _, pair := range []string{"ETH/USDT", "SOL/USDT"} {
+				snapshot, err := repo.GetOrderBookSnapshot(ctx, "Binance", pair)
+				require.NoError(t, err)
+				assert.Equal(t, []*models.DepthOrder{{Price: 1, BaseQty: 1}}, snapshot.OrderBook, pair)
+				assert.True(t, archived.Equal(snapshot.UpdatedAt), "%s updated_at %v", pair, snapshot.UpdatedAt)
+			}
+		})
+	}
+}
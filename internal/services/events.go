@@ -0,0 +1,91 @@
+package services
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"sync"
+)
+
+// Размер очереди событий подписчика. Подписчик, не успевающий забирать
+// события, отключается, чтобы не задерживать сохранение данных.
+const subscriptionBuffer = 64
+
+// Событие сохранения книги ордеров
+type OrderBookUpdate struct {
+	ExchangeName string
+	Pair         string
+	OrderBook    []*models.DepthOrder
+}
+
+// Подписка на события. Канал C закрывается после Close или отключения
+// подписчика, не успевавшего забирать события; в последнем случае Lagged
+// возвращает true.
+type Subscription[T any] struct {
+	C <-chan T
+
+	ch     chan T
+	filter func(T) bool
+	broker *broker[T]
+	lagged bool
+}
+
+// Метод для отмены подписки
+func (s *Subscription[T]) Close() {
+	s.broker.remove(s, false)
+}
+
+// Метод проверки, была ли подписка отключена из-за переполнения очереди
+func (s *Subscription[T]) Lagged() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.lagged
+}
+
+// Рассылка событий подписчикам в пределах процесса. Нулевое значение готово
+// к использованию.
+type broker[T any] struct {
+	mu   sync.Mutex
+	subs map[*Subscription[T]]struct{}
+}
+
+func (b *broker[T]) subscribe(filter func(T) bool) *Subscription[T] {
+	ch := make(chan T, subscriptionBuffer)
+	s := &Subscription[T]{C: ch, ch: ch, filter: filter, broker: b}
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[*Subscription[T]]struct{})
+	}
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *broker[T]) remove(s *Subscription[T], lagged bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(s, lagged)
+}
+
+func (b *broker[T]) removeLocked(s *Subscription[T], lagged bool) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	s.lagged = lagged
+	close(s.ch)
+}
+
+// Метод рассылки события подписчикам, фильтр которых его принимает
+func (b *broker[T]) publish(event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.filter(event) {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			b.removeLocked(s, true)
+		}
+	}
+}
@@ -3,11 +3,19 @@ package services
 import (
 	"StatisticsCollectionService/internal/models"
 	"StatisticsCollectionService/internal/repository"
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Структура сервиса, предоставляющая бизнес-логику
 type Service struct {
 	Repo repository.Repository
+
+	// Подписчики на сохранённые книги ордеров и ордера
+	orderBooks broker[OrderBookUpdate]
+	orders     broker[*models.HistoryOrder]
 }
 
 // Конструктор для создания нового сервиса
@@ -16,21 +24,216 @@ func NewService(repo repository.Repository) *Service {
 }
 
 // Метод для получения книги ордеров
-func (s *Service) GetOrderBook(exchangeName, pair string) ([]*models.DepthOrder, error) {
-	return s.Repo.GetOrderBook(exchangeName, pair)
+func (s *Service) GetOrderBook(ctx context.Context, exchangeName, pair string) (orderBook []*models.DepthOrder, err error) {
+	ctx, span := startSpan(ctx, "GetOrderBook", attrExchange.String(exchangeName), attrPair.String(pair))
+	defer func() { endSpan(span, err) }()
+
+	var v validator
+	v.required("exchange_name", exchangeName)
+	v.required("pair", pair)
+	if err = v.err(); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetOrderBook(ctx, exchangeName, pair)
+}
+
+// Метод для получения книги ордеров с версией и временем сохранения
+func (s *Service) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (snapshot *models.OrderBookSnapshot, err error) {
+	ctx, span := startSpan(ctx, "GetOrderBookSnapshot", attrExchange.String(exchangeName), attrPair.String(pair))
+	defer func() {
+		if snapshot != nil {
+			span.SetAttributes(attribute.Int64("version", snapshot.Version))
+		}
+		endSpan(span, err)
+	}()
+
+	var v validator
+	v.required("exchange_name", exchangeName)
+	v.required("pair", pair)
+	if err = v.err(); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetOrderBookSnapshot(ctx, exchangeName, pair)
 }
 
 // Метод для сохранения книги ордеров
-func (s *Service) SaveOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error {
-	return s.Repo.SaveOrderBook(exchangeName, pair, orderBook)
+func (s *Service) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) (err error) {
+	ctx, span := startSpan(ctx, "SaveOrderBook", attrExchange.String(exchangeName), attrPair.String(pair))
+	defer func() { endSpan(span, err) }()
+
+	if err = validateOrderBook(exchangeName, pair, orderBook); err != nil {
+		return err
+	}
+	if err = s.Repo.SaveOrderBook(ctx, exchangeName, pair, orderBook); err != nil {
+		return err
+	}
+	s.orderBooks.publish(OrderBookUpdate{ExchangeName: exchangeName, Pair: pair, OrderBook: orderBook})
+	return nil
+}
+
+// Метод для сохранения книги ордеров с проверкой версии. Если expectedVersion
+// больше нуля, книга сохраняется, только если её текущая версия совпадает с
+// ним, а при repository.AnyVersion — только если книга существует; иначе
+// возвращается repository.ErrVersionMismatch. Возвращает новый снимок книги.
+func (s *Service) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (snapshot *models.OrderBookSnapshot, err error) {
+	ctx, span := startSpan(ctx, "SaveOrderBookSnapshot", attrExchange.String(exchangeName), attrPair.String(pair),
+		attribute.Int64("expected_version", expectedVersion))
+	defer func() { endSpan(span, err) }()
+
+	if err = validateOrderBook(exchangeName, pair, orderBook); err != nil {
+		return nil, err
+	}
+	if snapshot, err = s.Repo.SaveOrderBookSnapshot(ctx, exchangeName, pair, orderBook, expectedVersion); err != nil {
+		return nil, err
+	}
+	s.orderBooks.publish(OrderBookUpdate{ExchangeName: exchangeName, Pair: pair, OrderBook: orderBook})
+	return snapshot, nil
+}
+
+// Функция проверки книги ордеров перед сохранением
+func validateOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	var v validator
+	v.required("exchange_name", exchangeName)
+	v.required("pair", pair)
+	for i, order := range orderBook {
+		field := fmt.Sprintf("order_book[%d]", i)
+		if order == nil {
+			v.add(field, "must not be null")
+			continue
+		}
+		v.nonNegative(field+".price", order.Price)
+		v.nonNegative(field+".base_qty", order.BaseQty)
+	}
+	return v.err()
 }
 
 // Метод для получения истории ордеров
-func (s *Service) GetOrderHistory(client *models.Client) ([]*models.HistoryOrder, error) {
-	return s.Repo.GetOrderHistory(client)
+func (s *Service) GetOrderHistory(ctx context.Context, client *models.Client) (history []*models.HistoryOrder, err error) {
+	ctx, span := startSpan(ctx, "GetOrderHistory", clientAttributes(client)...)
+	defer func() {
+		span.SetAttributes(attribute.Int("orders", len(history)))
+		endSpan(span, err)
+	}()
+
+	var v validator
+	if client == nil {
+		v.add("client", "is required")
+	} else {
+		v.required("client_name", client.ClientName)
+		if client.TimeFrom != nil && client.TimeTo != nil && !client.TimeFrom.Before(*client.TimeTo) {
+			v.add("time_to", "must be after time_from")
+		}
+	}
+	if err = v.err(); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetOrderHistory(ctx, client)
 }
 
 // Метод для сохранения ордера
-func (s *Service) SaveOrder(client *models.Client, order *models.HistoryOrder) error {
-	return s.Repo.SaveOrder(client, order)
+func (s *Service) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) (err error) {
+	attrs := clientAttributes(client)
+	if order != nil {
+		attrs = append(attrs, attrExchange.String(order.ExchangeName), attrPair.String(order.Pair))
+	}
+	ctx, span := startSpan(ctx, "SaveOrder", attrs...)
+	defer func() { endSpan(span, err) }()
+
+	if err = validateOrder(client, order); err != nil {
+		return err
+	}
+	if err = s.Repo.SaveOrder(ctx, client, order); err != nil {
+		return err
+	}
+	s.publishOrder(client, order)
+	return nil
+}
+
+// Метод для сохранения ордера вместе с книгой ордеров его биржи и пары валют.
+// Обе записи фиксируются одной транзакцией: если одна из них не удалась,
+// другая откатывается. Подписчики получают события только после фиксации.
+func (s *Service) SaveOrderWithOrderBook(ctx context.Context, client *models.Client, order *models.HistoryOrder, orderBook []*models.DepthOrder) (err error) {
+	attrs := clientAttributes(client)
+	if order != nil {
+		attrs = append(attrs, attrExchange.String(order.ExchangeName), attrPair.String(order.Pair))
+	}
+	ctx, span := startSpan(ctx, "SaveOrderWithOrderBook", attrs...)
+	defer func() { endSpan(span, err) }()
+
+	if err = validateOrder(client, order); err != nil {
+		return err
+	}
+	if err = validateOrderBook(order.ExchangeName, order.Pair, orderBook); err != nil {
+		return err
+	}
+	err = s.Repo.WithTx(ctx, func(tx repository.Repository) error {
+		if err := tx.SaveOrder(ctx, client, order); err != nil {
+			return err
+		}
+		return tx.SaveOrderBook(ctx, order.ExchangeName, order.Pair, orderBook)
+	})
+	if err != nil {
+		return err
+	}
+	s.publishOrder(client, order)
+	s.orderBooks.publish(OrderBookUpdate{ExchangeName: order.ExchangeName, Pair: order.Pair, OrderBook: orderBook})
+	return nil
+}
+
+// Функция проверки ордера перед сохранением
+func validateOrder(client *models.Client, order *models.HistoryOrder) error {
+	var v validator
+	if client == nil || order == nil {
+		v.add("order", "is required")
+	} else {
+		v.required("client_name", client.ClientName)
+		v.required("exchange_name", order.ExchangeName)
+		v.required("pair", order.Pair)
+		v.nonNegative("base_qty", order.BaseQty)
+		v.nonNegative("price", order.Price)
+		v.nonNegative("commission_quote_qty", order.CommissionQuoteQty)
+	}
+	return v.err()
+}
+
+// Метод уведомления подписчиков о сохранённом ордере
+func (s *Service) publishOrder(client *models.Client, order *models.HistoryOrder) {
+	saved := *order
+	saved.ClientName = client.ClientName
+	s.orders.publish(&saved)
+}
+
+// Метод для подписки на сохранение книги ордеров биржи и пары валют. События
+// приходят только о книгах, сохранённых через этот экземпляр сервиса.
+func (s *Service) SubscribeOrderBooks(exchangeName, pair string) (*Subscription[OrderBookUpdate], error) {
+	var v validator
+	v.required("exchange_name", exchangeName)
+	v.required("pair", pair)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return s.orderBooks.subscribe(func(update OrderBookUpdate) bool {
+		return update.ExchangeName == exchangeName && update.Pair == pair
+	}), nil
+}
+
+// Метод для подписки на новые ордера клиента. Непустые ExchangeName и Pair
+// ограничивают подписку биржей и парой валют. События приходят только об
+// ордерах, сохранённых через этот экземпляр сервиса.
+func (s *Service) SubscribeOrders(client *models.Client) (*Subscription[*models.HistoryOrder], error) {
+	var v validator
+	if client == nil {
+		v.add("client", "is required")
+	} else {
+		v.required("client_name", client.ClientName)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	filter := *client
+	return s.orders.subscribe(func(order *models.HistoryOrder) bool {
+		return order.ClientName == filter.ClientName &&
+			(filter.ExchangeName == "" || order.ExchangeName == filter.ExchangeName) &&
+			(filter.Pair == "" || order.Pair == filter.Pair)
+	}), nil
 }
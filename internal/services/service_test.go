@@ -1,9 +1,17 @@
 package services
 
 import (
+	"StatisticsCollectionService/config"
+	"StatisticsCollectionService/internal/db"
+	"StatisticsCollectionService/internal/migrations"
 	"StatisticsCollectionService/internal/models"
+	"StatisticsCollectionService/internal/repository"
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -12,26 +20,42 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetOrderBook(exchangeName, pair string) ([]*models.DepthOrder, error) {
-	args := m.Called(exchangeName, pair)
+func (m *MockRepository) GetOrderBook(ctx context.Context, exchangeName, pair string) ([]*models.DepthOrder, error) {
+	args := m.Called(ctx, exchangeName, pair)
 	return args.Get(0).([]*models.DepthOrder), args.Error(1)
 }
 
-func (m *MockRepository) SaveOrderBook(exchangeName, pair string, orderBook []*models.DepthOrder) error {
-	args := m.Called(exchangeName, pair, orderBook)
+func (m *MockRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	args := m.Called(ctx, exchangeName, pair, orderBook)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetOrderHistory(client *models.Client) ([]*models.HistoryOrder, error) {
-	args := m.Called(client)
+func (m *MockRepository) GetOrderBookSnapshot(ctx context.Context, exchangeName, pair string) (*models.OrderBookSnapshot, error) {
+	args := m.Called(ctx, exchangeName, pair)
+	snapshot, _ := args.Get(0).(*models.OrderBookSnapshot)
+	return snapshot, args.Error(1)
+}
+
+func (m *MockRepository) SaveOrderBookSnapshot(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder, expectedVersion int64) (*models.OrderBookSnapshot, error) {
+	args := m.Called(ctx, exchangeName, pair, orderBook, expectedVersion)
+	snapshot, _ := args.Get(0).(*models.OrderBookSnapshot)
+	return snapshot, args.Error(1)
+}
+
+func (m *MockRepository) GetOrderHistory(ctx context.Context, client *models.Client) ([]*models.HistoryOrder, error) {
+	args := m.Called(ctx, client)
 	return args.Get(0).([]*models.HistoryOrder), args.Error(1)
 }
 
-func (m *MockRepository) SaveOrder(client *models.Client, order *models.HistoryOrder) error {
-	args := m.Called(client, order)
+func (m *MockRepository) SaveOrder(ctx context.Context, client *models.Client, order *models.HistoryOrder) error {
+	args := m.Called(ctx, client, order)
 	return args.Error(0)
 }
 
+func (m *MockRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return fn(m)
+}
+
 func TestService_GetOrderBook(t *testing.T) {
 	mockRepo := new(MockRepository)
 	service := NewService(mockRepo)
@@ -42,8 +66,8 @@ func TestService_GetOrderBook(t *testing.T) {
 		{Price: 10.0, BaseQty: 1.0},
 		{Price: 15.0, BaseQty: 2.0},
 	}
-	mockRepo.On("GetOrderBook", exchangeName, pair).Return(expectedOrders, nil)
-	orders, err := service.GetOrderBook(exchangeName, pair)
+	mockRepo.On("GetOrderBook", mock.Anything, exchangeName, pair).Return(expectedOrders, nil)
+	orders, err := service.GetOrderBook(context.Background(), exchangeName, pair)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedOrders, orders)
 
@@ -61,12 +85,44 @@ func TestService_SaveOrderBook(t *testing.T) {
 		{Price: 15.0, BaseQty: 2.0},
 	}
 
-	mockRepo.On("SaveOrderBook", exchangeName, pair, orderBook).Return(nil)
-	err := service.SaveOrderBook(exchangeName, pair, orderBook)
+	mockRepo.On("SaveOrderBook", mock.Anything, exchangeName, pair, orderBook).Return(nil)
+	err := service.SaveOrderBook(context.Background(), exchangeName, pair, orderBook)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestService_SaveOrderBookSnapshot(t *testing.T) {
+	service := NewService(repository.NewInMemoryRepository())
+	sub, err := service.SubscribeOrderBooks("Binance", "BTC/USD")
+	assert.NoError(t, err)
+	defer sub.Close()
+	ctx := context.Background()
+
+	book := []*models.DepthOrder{{Price: 10, BaseQty: 1}}
+	saved, err := service.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USD", book, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), saved.Version)
+	<-sub.C
+
+	// Книга, не сохранённая из-за изменившейся версии, не публикуется
+	_, err = service.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USD", book, 2)
+	assert.ErrorIs(t, err, repository.ErrVersionMismatch)
+	assert.Empty(t, sub.C)
+
+	saved, err = service.SaveOrderBookSnapshot(ctx, "Binance", "BTC/USD", book, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), saved.Version)
+
+	snapshot, err := service.GetOrderBookSnapshot(ctx, "Binance", "BTC/USD")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, snapshot)
+
+	_, err = service.SaveOrderBookSnapshot(ctx, "", "BTC/USD", book, 1)
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = service.GetOrderBookSnapshot(ctx, "Binance", "")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
 func TestService_GetOrderHistory(t *testing.T) {
 	mockRepo := new(MockRepository)
 	service := NewService(mockRepo)
@@ -90,8 +146,8 @@ func TestService_GetOrderHistory(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("GetOrderHistory", client).Return(expectedHistory, nil)
-	history, err := service.GetOrderHistory(client)
+	mockRepo.On("GetOrderHistory", mock.Anything, client).Return(expectedHistory, nil)
+	history, err := service.GetOrderHistory(context.Background(), client)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedHistory, history)
 	mockRepo.AssertExpectations(t)
@@ -123,8 +179,176 @@ func TestService_SaveOrder(t *testing.T) {
 		Pair:         order.Pair,
 	}
 
-	mockRepo.On("SaveOrder", client, order).Return(nil)
-	err := service.SaveOrder(client, order)
+	mockRepo.On("SaveOrder", mock.Anything, client, order).Return(nil)
+	err := service.SaveOrder(context.Background(), client, order)
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+// Репозиторий, в транзакциях которого сохранение книги ордеров завершается ошибкой
+type failingOrderBookRepository struct {
+	repository.Repository
+	err error
+}
+
+func (r *failingOrderBookRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return r.Repository.WithTx(ctx, func(tx repository.Repository) error {
+		return fn(&failingOrderBookRepository{Repository: tx, err: r.err})
+	})
+}
+
+func (r *failingOrderBookRepository) SaveOrderBook(ctx context.Context, exchangeName, pair string, orderBook []*models.DepthOrder) error {
+	return r.err
+}
+
+func TestService_SaveOrderWithOrderBook(t *testing.T) {
+	backends := map[string]func(t *testing.T) repository.Repository{
+		"memory": func(t *testing.T) repository.Repository { return repository.NewInMemoryRepository() },
+		"sqlite": func(t *testing.T) repository.Repository {
+			conn, err := db.OpenSQLite(config.StorageConfig{SQLitePath: filepath.Join(t.TempDir(), "stats.db"), SQLiteBusyTimeout: 5 * time.Second})
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+			migrator, err := migrations.NewMigrator(conn, migrations.SQLite)
+			require.NoError(t, err)
+			_, err = migrator.Up(context.Background())
+			require.NoError(t, err)
+			return repository.NewSQLiteRepository(conn)
+		},
+	}
+	for name, newRepo := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo(t)
+			client := &models.Client{ClientName: "John Doe"}
+			order := &models.HistoryOrder{ExchangeName: "Binance", Label: "order1", Pair: "BTC/USD", Side: "buy", Type: "limit", BaseQty: 1, Price: 10, TimePlaced: time.Now().UTC()}
+			orderBook := []*models.DepthOrder{{Price: 10, BaseQty: 1}}
+
+			// Ошибка сохранения книги откатывает уже записанный ордер
+			saveErr := errors.New("order book write failed")
+			failing := NewService(&failingOrderBookRepository{Repository: repo, err: saveErr})
+			assert.ErrorIs(t, failing.SaveOrderWithOrderBook(ctx, client, order, orderBook), saveErr)
+			history, err := repo.GetOrderHistory(ctx, client)
+			require.NoError(t, err)
+			assert.Empty(t, history)
+
+			service := NewService(repo)
+			orders, err := service.SubscribeOrders(client)
+			require.NoError(t, err)
+			defer orders.Close()
+			require.NoError(t, service.SaveOrderWithOrderBook(ctx, client, order, orderBook))
+			history, err = repo.GetOrderHistory(ctx, client)
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			assert.Equal(t, "order1", history[0].Label)
+			saved, err := repo.GetOrderBook(ctx, "Binance", "BTC/USD")
+			require.NoError(t, err)
+			assert.Equal(t, orderBook, saved)
+			assert.Equal(t, "order1", (<-orders.C).Label)
+		})
+	}
+}
+
+func TestService_Validation(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo)
+	ctx := context.Background()
+
+	_, err := service.GetOrderBook(ctx, "", "BTC/USD")
+	assert.ErrorIs(t, err, ErrValidation)
+
+	err = service.SaveOrderBook(ctx, "Binance", "BTC/USD", []*models.DepthOrder{{Price: -1, BaseQty: 1}, nil})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{
+		{Field: "order_book[0].price", Message: "must not be negative"},
+		{Field: "order_book[1]", Message: "must not be null"},
+	}, validationErr.Fields)
+
+	_, err = service.GetOrderHistory(ctx, &models.Client{})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	_, err = service.GetOrderHistory(ctx, &models.Client{ClientName: "John Doe", TimeFrom: &from, TimeTo: &from})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "time_to", Message: "must be after time_from"}}, validationErr.Fields)
+
+	err = service.SaveOrder(ctx, &models.Client{ClientName: "John Doe"}, &models.HistoryOrder{ExchangeName: "Binance"})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "pair", Message: "is required"}}, validationErr.Fields)
+
+	mockRepo.AssertNotCalled(t, "GetOrderBook", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveOrderBook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "GetOrderHistory", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveOrder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SubscribeOrderBooks(t *testing.T) {
+	service := NewService(repository.NewInMemoryRepository())
+	sub, err := service.SubscribeOrderBooks("Binance", "BTC/USDT")
+	assert.NoError(t, err)
+
+	book := []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}}
+	assert.NoError(t, service.SaveOrderBook(context.Background(), "Binance", "ETH/USDT", book))
+	assert.NoError(t, service.SaveOrderBook(context.Background(), "Binance", "BTC/USDT", book))
+	assert.Equal(t, OrderBookUpdate{ExchangeName: "Binance", Pair: "BTC/USDT", OrderBook: book}, <-sub.C)
+	assert.Empty(t, sub.C)
+
+	// Несохранённая книга не публикуется
+	assert.Error(t, service.SaveOrderBook(context.Background(), "Binance", "BTC/USDT", []*models.DepthOrder{{Price: -1}}))
+	assert.Empty(t, sub.C)
+
+	sub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.False(t, sub.Lagged())
+
+	_, err = service.SubscribeOrderBooks("", "BTC/USDT")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_SubscribeOrders(t *testing.T) {
+	service := NewService(repository.NewInMemoryRepository())
+	all, err := service.SubscribeOrders(&models.Client{ClientName: "John Doe"})
+	assert.NoError(t, err)
+	eth, err := service.SubscribeOrders(&models.Client{ClientName: "John Doe", Pair: "ETH/USDT"})
+	assert.NoError(t, err)
+	defer all.Close()
+	defer eth.Close()
+
+	save := func(client, pair string) {
+		order := &models.HistoryOrder{ExchangeName: "Binance", Pair: pair, Label: client + " " + pair}
+		assert.NoError(t, service.SaveOrder(context.Background(), &models.Client{ClientName: client}, order))
+	}
+	save("John Doe", "BTC/USDT")
+	save("Jane Doe", "ETH/USDT")
+	save("John Doe", "ETH/USDT")
+
+	got := <-all.C
+	assert.Equal(t, "John Doe BTC/USDT", got.Label)
+	assert.Equal(t, "John Doe", got.ClientName)
+	assert.Equal(t, "John Doe ETH/USDT", (<-all.C).Label)
+	assert.Equal(t, "John Doe ETH/USDT", (<-eth.C).Label)
+	assert.Empty(t, all.C)
+	assert.Empty(t, eth.C)
+
+	_, err = service.SubscribeOrders(&models.Client{})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_SubscriptionLagged(t *testing.T) {
+	service := NewService(repository.NewInMemoryRepository())
+	sub, err := service.SubscribeOrderBooks("Binance", "BTC/USDT")
+	assert.NoError(t, err)
+
+	book := []*models.DepthOrder{{Price: 50000, BaseQty: 0.1}}
+	for i := 0; i <= subscriptionBuffer; i++ {
+		assert.NoError(t, service.SaveOrderBook(context.Background(), "Binance", "BTC/USDT", book))
+	}
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received)
+	assert.True(t, sub.Lagged())
+	sub.Close()
+}
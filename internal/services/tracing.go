@@ -0,0 +1,53 @@
+package services
+
+import (
+	"StatisticsCollectionService/internal/models"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Атрибуты spans сервиса
+const (
+	attrExchange = attribute.Key("exchange")
+	attrPair     = attribute.Key("pair")
+	attrClient   = attribute.Key("client")
+)
+
+var tracer = otel.Tracer("StatisticsCollectionService/internal/services")
+
+// Функция начала span метода сервиса
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Service."+method, trace.WithAttributes(attrs...))
+}
+
+// Функция завершения span. Ошибка проверки данных записывается в span,
+// но не помечает его как ошибочный: это ошибка клиента, а не сервиса.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, ErrValidation) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// Функция получения атрибутов span для клиента
+func clientAttributes(client *models.Client) []attribute.KeyValue {
+	if client == nil {
+		return nil
+	}
+	attrs := []attribute.KeyValue{attrClient.String(client.ClientName)}
+	if client.ExchangeName != "" {
+		attrs = append(attrs, attrExchange.String(client.ExchangeName))
+	}
+	if client.Pair != "" {
+		attrs = append(attrs, attrPair.String(client.Pair))
+	}
+	return attrs
+}
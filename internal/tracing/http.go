@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"StatisticsCollectionService/internal/api"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("StatisticsCollectionService/internal/tracing")
+
+// Middleware, создающее серверный span для каждого запроса. Контекст трассы
+// берётся из заголовков traceparent и tracestate, если клиент их передал.
+// Функция route возвращает шаблон маршрута запроса; пустая строка — маршрут
+// не найден.
+func Middleware(route func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name := route(r)
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		}
+		spanName := r.Method
+		if name != "" {
+			attrs = append(attrs, semconv.HTTPRoute(name))
+			spanName += " " + name
+		}
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		recorder := api.NewResponseRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
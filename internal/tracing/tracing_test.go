@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"StatisticsCollectionService/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Глобальный провайдер для тестов. Трассировщики пакетов привязываются к
+// первому установленному провайдеру, поэтому он создаётся один раз, а каждый
+// тест подключает к нему свой обработчик spans.
+var (
+	providerOnce sync.Once
+	provider     *sdktrace.TracerProvider
+)
+
+// Функция подключения обработчика, сохраняющего spans теста в памяти
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	providerOnce.Do(func() {
+		provider = sdktrace.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	recorder := tracetest.NewSpanRecorder()
+	provider.RegisterSpanProcessor(recorder)
+	t.Cleanup(func() { provider.UnregisterSpanProcessor(recorder) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	result := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		result[attr.Key] = attr.Value
+	}
+	return result
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := newRecorder(t)
+	var inner trace.SpanContext
+	handler := Middleware(func(r *http.Request) string { return "/orderhistory/get" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inner = trace.SpanContextFromContext(r.Context())
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orderhistory/get", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /orderhistory/get", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, span.SpanContext().SpanID(), inner.SpanID())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := attributes(span)
+	assert.Equal(t, "GET", attrs["http.request.method"].AsString())
+	assert.Equal(t, "/orderhistory/get", attrs["http.route"].AsString())
+	assert.Equal(t, int64(http.StatusInternalServerError), attrs["http.response.status_code"].AsInt64())
+}
+
+func TestMiddleware_Unmatched(t *testing.T) {
+	recorder := newRecorder(t)
+	handler := Middleware(func(r *http.Request) string { return "" }, http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.NotContains(t, attributes(spans[0]), attribute.Key("http.route"))
+}
+
+func TestSetup_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	cfg := config.Default().Tracing
+	cfg.Exporter = config.TracingFile
+	cfg.File = path
+	shutdown, err := Setup(context.Background(), cfg)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"work"`)
+	assert.Contains(t, string(data), "statistics-collection-service")
+}
+
+func TestSetup_Errors(t *testing.T) {
+	cfg := config.Default().Tracing
+	cfg.Exporter = "jaeger"
+	_, err := Setup(context.Background(), cfg)
+	assert.Error(t, err)
+
+	cfg.Exporter = config.TracingFile
+	cfg.File = filepath.Join(t.TempDir(), "missing", "traces.json")
+	_, err = Setup(context.Background(), cfg)
+	assert.Error(t, err)
+}